@@ -14,6 +14,7 @@ import (
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
 
 	"github.com/capitalize-ai/conversational-platform/internal/config"
 	"github.com/capitalize-ai/conversational-platform/internal/handler"
@@ -22,6 +23,7 @@ import (
 	natsclient "github.com/capitalize-ai/conversational-platform/internal/nats"
 	"github.com/capitalize-ai/conversational-platform/internal/service"
 	"github.com/capitalize-ai/conversational-platform/pkg/logger"
+	"github.com/capitalize-ai/conversational-platform/pkg/metrics"
 	"github.com/capitalize-ai/conversational-platform/pkg/tracing"
 )
 
@@ -60,7 +62,7 @@ func main() {
 		Token:    cfg.NATSToken,
 	}, log)
 	if err != nil {
-		log.Error("failed to connect to NATS", "error", err)
+		log.Error("failed to connect to NATS", zap.Error(err))
 		os.Exit(1)
 	}
 	defer natsClient.Close()
@@ -68,31 +70,172 @@ func main() {
 	// Ensure JetStream stream exists
 	streamManager := natsclient.NewStreamManager(natsClient)
 	if err := streamManager.EnsureStream(ctx); err != nil {
-		log.Error("failed to ensure stream", "error", err)
+		log.Error("failed to ensure stream", zap.Error(err))
 		os.Exit(1)
 	}
 
-	// Initialize LLM client
-	var llmClient llm.Client
+	// Ensure generation job stream exists
+	if err := streamManager.EnsureJobStream(ctx); err != nil {
+		log.Error("failed to ensure job stream", zap.Error(err))
+		os.Exit(1)
+	}
+
+	// Open conversation store
+	conversationStore, err := natsclient.NewConversationStore(ctx, natsClient)
+	if err != nil {
+		log.Error("failed to open conversation store", zap.Error(err))
+		os.Exit(1)
+	}
+
+	// Open prompt template store
+	templateStore, err := natsclient.NewTemplateStore(ctx, natsClient)
+	if err != nil {
+		log.Error("failed to open template store", zap.Error(err))
+		os.Exit(1)
+	}
+
+	// Open tool definition store
+	toolStore, err := natsclient.NewToolStore(ctx, natsClient)
+	if err != nil {
+		log.Error("failed to open tool store", zap.Error(err))
+		os.Exit(1)
+	}
+
+	// Tenant attachment buckets are created on first use
+	attachmentStore := natsclient.NewAttachmentStore(natsClient, cfg.AttachmentTenantQuota)
+
+	// Rebuild the conversation index from the stream tail
+	checkpointStore, err := natsclient.NewCheckpointStore(ctx, natsClient)
+	if err != nil {
+		log.Error("failed to open checkpoint store", zap.Error(err))
+		os.Exit(1)
+	}
+	projector := service.NewConversationProjector(streamManager, conversationStore, checkpointStore, log)
+	if err := projector.CatchUp(ctx); err != nil {
+		log.Error("failed to rebuild conversation index", zap.Error(err))
+		os.Exit(1)
+	}
+
+	// Open buffer for in-progress generations
+	generationBuffer, err := natsclient.NewGenerationBuffer(ctx, natsClient, cfg.GenerationBufferTTL)
+	if err != nil {
+		log.Error("failed to open generation buffer", zap.Error(err))
+		os.Exit(1)
+	}
+
+	// Open store of jobs cancelled while queued
+	cancelStore, err := natsclient.NewCancelStore(ctx, natsClient)
+	if err != nil {
+		log.Error("failed to open cancel store", zap.Error(err))
+		os.Exit(1)
+	}
+
+	// Register every configured LLM provider
+	registry := llm.NewRegistry(cfg.DefaultLLM)
+	if cfg.DefaultLLM == "fake" {
+		registry.Register(llm.NewFakeClient(llm.FakeConfig{
+			Responses:  cfg.FakeLLMResponses,
+			TokenDelay: cfg.FakeLLMTokenDelay,
+			Fail:       cfg.FakeLLMFail,
+		}))
+		log.Warn("fake LLM provider enabled, responses are not generated by a model")
+	}
 	if cfg.AnthropicAPIKey != "" {
-		llmClient, err = llm.NewAnthropicClient(cfg.AnthropicAPIKey)
+		anthropicClient, err := llm.NewAnthropicClient(cfg.AnthropicAPIKey)
 		if err != nil {
-			log.Warn("failed to create Anthropic client, LLM features disabled")
+			log.Warn("failed to create Anthropic client", zap.Error(err))
+		} else {
+			registry.Register(anthropicClient)
+		}
+	}
+	if cfg.OpenAIAPIKey != "" {
+		openAIClient, err := llm.NewOpenAIClient(cfg.OpenAIAPIKey)
+		if err != nil {
+			log.Warn("failed to create OpenAI client", zap.Error(err))
+		} else {
+			registry.Register(openAIClient)
 		}
-	} else if cfg.OpenAIAPIKey != "" {
-		llmClient, err = llm.NewOpenAIClient(cfg.OpenAIAPIKey)
+	}
+	if cfg.LocalLLMURL != "" {
+		localClient, err := llm.NewLocalClient(ctx, cfg.LocalLLMName, cfg.LocalLLMURL, cfg.LocalLLMAPIKey)
 		if err != nil {
-			log.Warn("failed to create OpenAI client, LLM features disabled")
+			log.Warn("failed to create local LLM client", zap.Error(err))
+		} else {
+			registry.Register(localClient)
+			log.Info("local LLM provider configured",
+				zap.String("url", cfg.LocalLLMURL),
+				zap.Strings("models", localClient.Models()),
+			)
+		}
+	}
+
+	var llmClient llm.Client
+	var breakers *llm.Breakers
+	if registry.Len() > 0 {
+		breakers = llm.NewBreakers(llm.BreakerConfig{
+			Window:         cfg.BreakerWindow,
+			MinRequests:    cfg.BreakerMinRequests,
+			ErrorRate:      cfg.BreakerErrorRate,
+			SlowThreshold:  cfg.BreakerSlowThreshold,
+			OpenDuration:   cfg.BreakerOpenDuration,
+			HalfOpenProbes: cfg.BreakerProbes,
+		})
+		breakers.OnStateChange = func(provider, model string, state llm.BreakerState) {
+			metrics.RecordCircuitState(provider, model, state.String(), float64(state))
+			log.Warn("LLM circuit breaker changed state",
+				zap.String("provider", provider),
+				zap.String("model", model),
+				zap.String("state", state.String()),
+			)
 		}
+		llmClient = llm.NewFailoverClient(registry, breakers, llm.RetryPolicy{
+			MaxAttempts:    cfg.LLMMaxAttempts,
+			InitialBackoff: cfg.LLMRetryBackoff,
+			MaxBackoff:     cfg.LLMMaxBackoff,
+		})
+		log.Info("LLM providers configured",
+			zap.String("default_provider", registry.Default().Name()),
+			zap.String("default_model", llm.DefaultModel(registry)),
+		)
+	} else {
+		log.Warn("no LLM provider configured, LLM features disabled")
 	}
 
 	// Initialize services
-	conversationSvc := service.NewConversationService(streamManager, log)
-	messageSvc := service.NewMessageService(streamManager, conversationSvc, llmClient, log)
+	templateSvc := service.NewTemplateService(templateStore, log)
+	conversationSvc := service.NewConversationService(streamManager, conversationStore, templateSvc, llmClient, log)
+	liveRelay := natsclient.NewLiveRelay(natsClient)
+	summarizer := service.NewSummarizer(streamManager, llmClient, service.SummarizerConfig{
+		Threshold:  cfg.SummaryThreshold,
+		KeepRecent: cfg.SummaryKeepRecent,
+	}, log)
+	toolSvc := service.NewToolService(toolStore, service.ToolConfig{
+		Timeout:       cfg.ToolTimeout,
+		MaxIterations: cfg.ToolMaxIterations,
+	}, log)
+	attachmentSvc := service.NewAttachmentService(attachmentStore, service.AttachmentConfig{
+		MaxBytes:    cfg.AttachmentMaxBytes,
+		TenantQuota: cfg.AttachmentTenantQuota,
+	}, log)
+	messageSvc := service.NewMessageService(streamManager, liveRelay, generationBuffer, cancelStore, conversationSvc, summarizer, toolSvc, attachmentSvc, llmClient, cfg.LLMFallbacks, cfg.ThinkingHiddenTenants, log)
+
+	// Start generation worker
+	generationWorker := service.NewGenerationWorker(streamManager, messageSvc, service.WorkerConfig{
+		Concurrency:        cfg.WorkerConcurrency,
+		Timeout:            cfg.GenerationTimeout,
+		SummaryConcurrency: cfg.SummaryConcurrency,
+	}, log)
+	if err := generationWorker.Start(ctx); err != nil {
+		log.Error("failed to start generation worker", zap.Error(err))
+		os.Exit(1)
+	}
 
 	// Initialize handlers
-	healthHandler := handler.NewHealthHandler(natsClient)
+	healthHandler := handler.NewHealthHandler(natsClient, breakers)
 	conversationHandler := handler.NewConversationHandler(conversationSvc, log)
+	templateHandler := handler.NewTemplateHandler(templateSvc, log)
+	toolHandler := handler.NewToolHandler(toolSvc, log)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentSvc, conversationSvc, log)
 	messageHandler := handler.NewMessageHandler(messageSvc, conversationSvc, log)
 	streamHandler := handler.NewStreamHandler(messageSvc, conversationSvc, log)
 
@@ -140,9 +283,39 @@ func main() {
 				r.Get("/messages", messageHandler.List)
 				r.Post("/messages", messageHandler.Send)
 
+				// Attachments
+				r.Post("/attachments", attachmentHandler.Upload)
+				r.Get("/attachments/{attachmentID}", attachmentHandler.Get)
+
 				// Streaming
 				r.Get("/stream", streamHandler.Stream)
 				r.Post("/stream", streamHandler.StreamWithMessage)
+				r.Post("/cancel", streamHandler.Cancel)
+			})
+		})
+
+		// Prompt templates
+		r.Route("/templates", func(r chi.Router) {
+			r.Post("/", templateHandler.Create)
+			r.Get("/", templateHandler.List)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.Get("/", templateHandler.Get)
+				r.Put("/", templateHandler.Update)
+				r.Delete("/", templateHandler.Delete)
+				r.Get("/versions/{version}", templateHandler.Get)
+			})
+		})
+
+		// Tools
+		r.Route("/tools", func(r chi.Router) {
+			r.Post("/", toolHandler.Create)
+			r.Get("/", toolHandler.List)
+
+			r.Route("/{name}", func(r chi.Router) {
+				r.Get("/", toolHandler.Get)
+				r.Put("/", toolHandler.Update)
+				r.Delete("/", toolHandler.Delete)
 			})
 		})
 	})
@@ -158,9 +331,9 @@ func main() {
 
 	// Start server in goroutine
 	go func() {
-		log.Info("server listening", "port", cfg.ServerPort)
+		log.Info("server listening", zap.String("port", cfg.ServerPort))
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Error("server error", "error", err)
+			log.Error("server error", zap.Error(err))
 			os.Exit(1)
 		}
 	}()
@@ -177,8 +350,11 @@ func main() {
 	defer cancel()
 
 	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Error("server forced to shutdown", "error", err)
+		log.Error("server forced to shutdown", zap.Error(err))
 	}
 
+	// Let in-flight generations finish; unfinished jobs are redelivered elsewhere
+	generationWorker.Stop(shutdownCtx)
+
 	log.Info("server stopped")
 }
@@ -2,6 +2,7 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
 	"strconv"
 	"time"
@@ -28,7 +29,48 @@ type Config struct {
 	// LLM settings
 	AnthropicAPIKey string
 	OpenAIAPIKey    string
+	LocalLLMURL     string
+	LocalLLMAPIKey  string
+	LocalLLMName    string
 	DefaultLLM      string
+	LLMFallbacks    map[string][]string
+	LLMMaxAttempts  int
+	LLMRetryBackoff time.Duration
+	LLMMaxBackoff   time.Duration
+
+	// Fake LLM settings, used when DefaultLLM is "fake"
+	FakeLLMResponses  []string
+	FakeLLMTokenDelay time.Duration
+	FakeLLMFail       string
+
+	// Tool calling settings
+	ToolMaxIterations int
+	ToolTimeout       time.Duration
+
+	// Attachment settings
+	AttachmentMaxBytes    int64
+	AttachmentTenantQuota int64
+
+	// Tenants whose users are not shown the model's extended thinking
+	ThinkingHiddenTenants []string
+
+	// Circuit breaker settings
+	BreakerWindow        time.Duration
+	BreakerMinRequests   int
+	BreakerErrorRate     float64
+	BreakerSlowThreshold time.Duration
+	BreakerOpenDuration  time.Duration
+	BreakerProbes        int
+
+	// Generation settings
+	GenerationBufferTTL time.Duration
+	WorkerConcurrency   int
+	GenerationTimeout   time.Duration
+
+	// Summarization settings
+	SummaryThreshold   int
+	SummaryKeepRecent  int
+	SummaryConcurrency int
 
 	// Rate limiting
 	RateLimitRequests int
@@ -64,7 +106,48 @@ func Load() *Config {
 		// LLM
 		AnthropicAPIKey: getEnv("ANTHROPIC_API_KEY", ""),
 		OpenAIAPIKey:    getEnv("OPENAI_API_KEY", ""),
+		LocalLLMURL:     getEnv("LOCAL_LLM_URL", ""),
+		LocalLLMAPIKey:  getEnv("LOCAL_LLM_API_KEY", ""),
+		LocalLLMName:    getEnv("LOCAL_LLM_NAME", "local"),
 		DefaultLLM:      getEnv("DEFAULT_LLM", "anthropic"),
+		LLMFallbacks:    getJSONEnv("LLM_FALLBACKS", map[string][]string{}),
+		LLMMaxAttempts:  getIntEnv("LLM_MAX_ATTEMPTS", 3),
+		LLMRetryBackoff: getDurationEnv("LLM_RETRY_BACKOFF", 500*time.Millisecond),
+		LLMMaxBackoff:   getDurationEnv("LLM_MAX_BACKOFF", 10*time.Second),
+
+		// Fake LLM
+		FakeLLMResponses:  getJSONEnv("FAKE_LLM_RESPONSES", []string{}),
+		FakeLLMTokenDelay: getDurationEnv("FAKE_LLM_TOKEN_DELAY", 20*time.Millisecond),
+		FakeLLMFail:       getEnv("FAKE_LLM_FAIL", ""),
+
+		// Tool calling
+		ToolMaxIterations: getIntEnv("TOOL_MAX_ITERATIONS", 5),
+		ToolTimeout:       getDurationEnv("TOOL_WEBHOOK_TIMEOUT", 10*time.Second),
+
+		// Attachments
+		AttachmentMaxBytes:    int64(getIntEnv("ATTACHMENT_MAX_BYTES", 20*1024*1024)),
+		AttachmentTenantQuota: int64(getIntEnv("ATTACHMENT_TENANT_QUOTA", 1024*1024*1024)),
+
+		// Extended thinking
+		ThinkingHiddenTenants: getJSONEnv("THINKING_HIDDEN_TENANTS", []string{}),
+
+		// Circuit breaker
+		BreakerWindow:        getDurationEnv("LLM_BREAKER_WINDOW", time.Minute),
+		BreakerMinRequests:   getIntEnv("LLM_BREAKER_MIN_REQUESTS", 10),
+		BreakerErrorRate:     getFloatEnv("LLM_BREAKER_ERROR_RATE", 0.5),
+		BreakerSlowThreshold: getDurationEnv("LLM_BREAKER_SLOW_THRESHOLD", 30*time.Second),
+		BreakerOpenDuration:  getDurationEnv("LLM_BREAKER_OPEN_DURATION", 30*time.Second),
+		BreakerProbes:        getIntEnv("LLM_BREAKER_PROBES", 1),
+
+		// Generation
+		GenerationBufferTTL: getDurationEnv("GENERATION_BUFFER_TTL", 10*time.Minute),
+		WorkerConcurrency:   getIntEnv("WORKER_CONCURRENCY", 8),
+		GenerationTimeout:   getDurationEnv("GENERATION_TIMEOUT", 5*time.Minute),
+
+		// Summarization
+		SummaryThreshold:   getIntEnv("SUMMARY_THRESHOLD_TOKENS", 16000),
+		SummaryKeepRecent:  getIntEnv("SUMMARY_KEEP_RECENT_TOKENS", 4000),
+		SummaryConcurrency: getIntEnv("SUMMARY_CONCURRENCY", 2),
 
 		// Rate limiting
 		RateLimitRequests: getIntEnv("RATE_LIMIT_REQUESTS", 60),
@@ -95,6 +178,15 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
 func getBoolEnv(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if b, err := strconv.ParseBool(value); err == nil {
@@ -112,3 +204,13 @@ func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+func getJSONEnv[T any](key string, defaultValue T) T {
+	if value := os.Getenv(key); value != "" {
+		var v T
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			return v
+		}
+	}
+	return defaultValue
+}
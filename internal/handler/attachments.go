@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/capitalize-ai/conversational-platform/internal/middleware"
+	"github.com/capitalize-ai/conversational-platform/internal/service"
+	"github.com/capitalize-ai/conversational-platform/pkg/logger"
+)
+
+// AttachmentHandler handles attachment endpoints.
+type AttachmentHandler struct {
+	attachmentService   *service.AttachmentService
+	conversationService *service.ConversationService
+	logger              *logger.Logger
+}
+
+// NewAttachmentHandler creates a new attachment handler.
+func NewAttachmentHandler(
+	attachmentSvc *service.AttachmentService,
+	convSvc *service.ConversationService,
+	log *logger.Logger,
+) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService:   attachmentSvc,
+		conversationService: convSvc,
+		logger:              log,
+	}
+}
+
+// Upload handles POST /api/v1/conversations/:id/attachments
+//
+// The file is sent as the "file" part of a multipart/form-data body and is
+// streamed to the store without being buffered.
+func (h *AttachmentHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+	conversationID := chi.URLParam(r, "id")
+
+	if err := middleware.ValidateConversationID(conversationID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Verify conversation exists and belongs to tenant
+	if _, err := h.conversationService.Get(ctx, tenantID, conversationID); err != nil {
+		writeError(w, http.StatusNotFound, "conversation not found")
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "request body must be multipart/form-data")
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			writeError(w, http.StatusBadRequest, "missing file part")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid multipart body")
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		filename := filepath.Base(part.FileName())
+		if err := middleware.ValidateFilename(filename); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		att, err := h.attachmentService.Upload(ctx, tenantID, conversationID, filename, part)
+		if err != nil {
+			h.writeAttachmentError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, att)
+		return
+	}
+}
+
+// Get handles GET /api/v1/conversations/:id/attachments/:attachmentID
+func (h *AttachmentHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+	conversationID := chi.URLParam(r, "id")
+	attachmentID := chi.URLParam(r, "attachmentID")
+
+	if err := middleware.ValidateConversationID(conversationID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := middleware.ValidateAttachmentID(attachmentID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	att, content, err := h.attachmentService.Open(ctx, tenantID, conversationID, attachmentID)
+	if err != nil {
+		h.writeAttachmentError(w, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", att.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(att.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename}))
+	w.Header().Set("ETag", strconv.Quote(att.SHA256))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, content); err != nil {
+		h.logger.Warn("failed to send attachment",
+			zap.String("attachment_id", attachmentID),
+			zap.Error(err),
+		)
+	}
+}
+
+// writeAttachmentError maps attachment service errors to HTTP responses.
+func (h *AttachmentHandler) writeAttachmentError(w http.ResponseWriter, err error) {
+	if !writeAttachmentError(w, err) {
+		h.logger.Error("attachment store failed", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "attachment store unavailable")
+	}
+}
+
+// writeAttachmentError writes the response for an attachment the request
+// cannot use, reporting whether err was one.
+func writeAttachmentError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrAttachmentNotFound):
+		writeError(w, http.StatusNotFound, "attachment not found")
+	case errors.Is(err, service.ErrAttachmentTooLarge), errors.Is(err, service.ErrAttachmentQuota):
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, service.ErrUnsupportedAttachment):
+		writeError(w, http.StatusUnsupportedMediaType, err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -3,6 +3,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -45,8 +46,28 @@ func (h *ConversationHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Profile != nil {
+		if err := middleware.ValidateSystemPrompt(req.Profile.SystemPrompt); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if req.Template != nil {
+		if err := middleware.ValidateTemplateID(req.Template.ID); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
 	conv, err := h.service.Create(ctx, tenantID, userID, &req)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidProfile) ||
+			errors.Is(err, service.ErrInvalidTemplate) ||
+			errors.Is(err, service.ErrTemplateNotFound) {
+			writeConversationError(w, err)
+			return
+		}
 		h.logger.Error("failed to create conversation")
 		writeError(w, http.StatusInternalServerError, "failed to create conversation")
 		return
@@ -98,7 +119,7 @@ func (h *ConversationHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	conv, err := h.service.Get(ctx, tenantID, conversationID)
 	if err != nil {
-		writeError(w, http.StatusNotFound, "conversation not found")
+		writeConversationError(w, err)
 		return
 	}
 
@@ -129,9 +150,16 @@ func (h *ConversationHandler) Update(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if req.Profile != nil {
+		if err := middleware.ValidateSystemPrompt(req.Profile.SystemPrompt); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
 	conv, err := h.service.Update(ctx, tenantID, conversationID, &req)
 	if err != nil {
-		writeError(w, http.StatusNotFound, "conversation not found")
+		writeConversationError(w, err)
 		return
 	}
 
@@ -150,9 +178,25 @@ func (h *ConversationHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.service.Delete(ctx, tenantID, conversationID); err != nil {
-		writeError(w, http.StatusNotFound, "conversation not found")
+		writeConversationError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// writeConversationError maps conversation service errors to HTTP responses.
+func writeConversationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrConversationNotFound):
+		writeError(w, http.StatusNotFound, "conversation not found")
+	case errors.Is(err, service.ErrConversationConflict):
+		writeError(w, http.StatusConflict, "conversation was modified concurrently")
+	case errors.Is(err, service.ErrInvalidProfile), errors.Is(err, service.ErrInvalidTemplate):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrTemplateNotFound):
+		writeError(w, http.StatusNotFound, "template not found")
+	default:
+		writeError(w, http.StatusInternalServerError, "conversation store unavailable")
+	}
+}
@@ -3,18 +3,22 @@ package handler
 import (
 	"net/http"
 
+	"github.com/capitalize-ai/conversational-platform/internal/llm"
 	natsclient "github.com/capitalize-ai/conversational-platform/internal/nats"
 )
 
 // HealthHandler handles health check endpoints.
 type HealthHandler struct {
 	natsClient *natsclient.Client
+	breakers   *llm.Breakers
 }
 
-// NewHealthHandler creates a new health handler.
-func NewHealthHandler(natsClient *natsclient.Client) *HealthHandler {
+// NewHealthHandler creates a new health handler. breakers may be nil when no
+// LLM provider is configured.
+func NewHealthHandler(natsClient *natsclient.Client, breakers *llm.Breakers) *HealthHandler {
 	return &HealthHandler{
 		natsClient: natsClient,
+		breakers:   breakers,
 	}
 }
 
@@ -26,6 +30,8 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 }
 
 // Ready handles GET /ready
+// LLM circuit breaker states are reported but do not affect readiness: the
+// API still serves history and queues turns while a provider is down.
 func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
 	// Check NATS connection
 	if h.natsClient == nil || !h.natsClient.IsConnected() {
@@ -36,7 +42,12 @@ func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{
+	resp := map[string]any{
 		"status": "ready",
-	})
+	}
+	if h.breakers != nil {
+		resp["llm_circuits"] = h.breakers.Status()
+	}
+
+	writeJSON(w, http.StatusOK, resp)
 }
@@ -2,10 +2,12 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
 
 	"github.com/capitalize-ai/conversational-platform/internal/middleware"
 	"github.com/capitalize-ai/conversational-platform/internal/model"
@@ -99,18 +101,54 @@ func (h *MessageHandler) Send(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := middleware.ValidateMessageContent(req.Content); err != nil {
+	if err := middleware.ValidateSendMessage(&req); err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	if err := h.messageService.ValidateModel(req.Model); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.messageService.ValidateResponseFormat(req.ResponseFormat); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.messageService.ResolveAttachments(ctx, tenantID, conversationID, &req); err != nil {
+		if !writeAttachmentError(w, err) {
+			h.logger.Error("failed to resolve attachments", zap.Error(err))
+			writeError(w, http.StatusInternalServerError, "attachment store unavailable")
+		}
+		return
+	}
+
+	// Refuse up front rather than queue a turn for a provider that is down
+	if req.Stream {
+		if err := h.messageService.CheckAvailable(ctx, tenantID, conversationID, req.Model); err != nil {
+			if !writeUnavailable(w, err) {
+				writeConversationError(w, err)
+			}
+			return
+		}
+	}
+
 	if req.Stream {
-		// For streaming, return 202 Accepted with stream URL
-		w.Header().Set("X-Stream-URL", "/api/v1/conversations/"+conversationID+"/stream")
-		w.WriteHeader(http.StatusAccepted)
+		// For streaming, queue the generation and point the client at the
+		// live stream, starting right after its own message
+		userMsg, _, err := h.messageService.Enqueue(ctx, tenantID, conversationID, &req)
+		if err != nil {
+			h.logger.Error("failed to enqueue message", zap.Error(err))
+			writeError(w, http.StatusInternalServerError, "failed to send message")
+			return
+		}
 
-		// Store the pending request for the stream handler
-		// In production, this would use a proper queue or state store
+		w.Header().Set("X-Stream-URL", fmt.Sprintf("/api/v1/conversations/%s/stream?after_sequence=%d", conversationID, userMsg.Sequence))
+		writeJSON(w, http.StatusAccepted, &model.SendMessageResponse{
+			Message:  userMsg,
+			Sequence: userMsg.Sequence,
+		})
 		return
 	}
 
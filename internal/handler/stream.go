@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
 
 	"github.com/capitalize-ai/conversational-platform/internal/middleware"
 	"github.com/capitalize-ai/conversational-platform/internal/model"
@@ -42,8 +44,17 @@ type ReplayCompleteEvent struct {
 	MessageCount int    `json:"message_count"`
 }
 
+// GenerationRestartEvent tells the client to discard the tokens it has of a
+// generation, which is being streamed again from index 0 under a new attempt.
+type GenerationRestartEvent struct {
+	MessageID string `json:"message_id"`
+	Attempt   int    `json:"attempt"`
+}
+
 // Stream handles GET /api/v1/conversations/:id/stream
-// Supports ?after_sequence=N for resuming from a specific point
+// Supports ?after_sequence=N for resuming from a specific point and
+// ?after_token=K (and ?after_attempt=A, the attempt the token belongs to) for
+// resuming a generation that is still in progress
 func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	tenantID := middleware.GetTenantID(ctx)
@@ -69,6 +80,22 @@ func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Parse after_token query param for resuming an in-progress generation
+	afterToken := -1
+	if tokStr := r.URL.Query().Get("after_token"); tokStr != "" {
+		tok, err := strconv.Atoi(tokStr)
+		if err == nil && tok >= 0 {
+			afterToken = tok
+		}
+	}
+	afterAttempt := -1
+	if attStr := r.URL.Query().Get("after_attempt"); attStr != "" {
+		att, err := strconv.Atoi(attStr)
+		if err == nil && att >= 0 {
+			afterAttempt = att
+		}
+	}
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -93,15 +120,18 @@ func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
 		"conversation_id": conversationID,
 	})
 
-	// Replay missed messages if after_sequence is provided or replay all if 0
+	// Replay missed messages if after_sequence is provided or replay all if 0.
+	// cursor tracks the last sequence the client has seen so the live phase
+	// can pick up exactly where the replay stopped.
 	var lastSequence uint64
 	var totalReplayed int
+	cursor := afterSequence
 
 	for {
 		// Fetch messages in batches
 		resp, err := h.messageService.GetMessages(ctx, tenantID, conversationID, afterSequence, 50)
 		if err != nil {
-			h.logger.Error("failed to replay messages", "error", err, "conversation_id", conversationID)
+			h.logger.Error("failed to replay messages", zap.Error(err), zap.String("conversation_id", conversationID))
 			sendSSEEvent(w, flusher, "error", &model.ErrorEvent{
 				Code:    "replay_error",
 				Message: "Failed to replay messages",
@@ -119,6 +149,7 @@ func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
 
 			sendSSEEvent(w, flusher, "message", msg)
 			lastSequence = msg.Sequence
+			cursor = msg.Sequence
 			totalReplayed++
 		}
 
@@ -137,11 +168,40 @@ func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
 	})
 
 	h.logger.Info("message replay complete",
-		"conversation_id", conversationID,
-		"messages_replayed", totalReplayed,
-		"last_sequence", lastSequence,
+		zap.String("conversation_id", conversationID),
+		zap.Int("messages_replayed", totalReplayed),
+		zap.Uint64("last_sequence", lastSequence),
 	)
 
+	// Subscribe to everything published after the replay cursor
+	live, err := h.messageService.Follow(ctx, tenantID, conversationID, cursor)
+	if err != nil {
+		h.logger.Error("failed to follow conversation", zap.Error(err), zap.String("conversation_id", conversationID))
+		sendSSEEvent(w, flusher, "error", &model.ErrorEvent{
+			Code:    "live_error",
+			Message: "Failed to subscribe to live updates",
+		})
+		return
+	}
+
+	// Send the missing range of the newest generation in progress; any other
+	// is caught up from its buffer when its next token arrives. The live
+	// subscription is already open, so tokens emitted meanwhile are queued
+	// and deduplicated against this catch-up.
+	tokens := make(tokenCursor)
+	if partial, err := h.messageService.ActiveGeneration(ctx, tenantID, conversationID); err != nil {
+		h.logger.Warn("failed to load partial generation", zap.Error(err), zap.String("conversation_id", conversationID))
+	} else if partial != nil {
+		next := afterToken + 1
+		if afterAttempt >= 0 && afterAttempt != partial.Attempt {
+			// The tokens the client has belong to an abandoned attempt
+			sendSSEEvent(w, flusher, "restart", &GenerationRestartEvent{MessageID: partial.MessageID, Attempt: partial.Attempt})
+			next = 0
+		}
+		tokens[partial.MessageID] = tokenPosition{attempt: partial.Attempt, next: next}
+		tokens.sendFrom(w, flusher, partial)
+	}
+
 	// Start heartbeat ticker for keeping connection alive
 	heartbeat := time.NewTicker(30 * time.Second)
 	defer heartbeat.Stop()
@@ -151,9 +211,38 @@ func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
 		select {
 		case <-done:
 			// Client disconnected
-			h.logger.Info("SSE client disconnected", "conversation_id", conversationID)
+			h.logger.Info("SSE client disconnected", zap.String("conversation_id", conversationID))
 			return
 
+		case update, ok := <-live:
+			if !ok {
+				// Subscription ended; the client reconnects with after_sequence
+				sendSSEEvent(w, flusher, "error", &model.ErrorEvent{
+					Code:    "live_error",
+					Message: "Live subscription ended",
+				})
+				return
+			}
+
+			switch {
+			case update.Token != nil && update.Token.Thinking:
+				// Thinking is live only; resuming clients do not get it back
+				sendSSEEvent(w, flusher, "thinking", update.Token)
+			case update.Token != nil:
+				h.sendLiveToken(ctx, w, flusher, tenantID, conversationID, tokens, update.Token)
+			case update.Message != nil && update.Message.Role == model.RoleAssistant:
+				tokens.complete(update.Message.ID)
+				sendSSEEvent(w, flusher, "message_complete", &model.MessageCompleteEvent{
+					Message:  *update.Message,
+					Sequence: update.Sequence,
+					Output:   update.Message.StructuredOutput(),
+				})
+			case update.Message != nil:
+				sendSSEEvent(w, flusher, "message", update.Message)
+			case update.Event != nil:
+				sendSSEEvent(w, flusher, "event", update.Event)
+			}
+
 		case <-heartbeat.C:
 			// Send heartbeat to keep connection alive
 			sendSSEEvent(w, flusher, "heartbeat", &model.HeartbeatEvent{
@@ -163,6 +252,116 @@ func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Cancel handles POST /api/v1/conversations/:id/cancel
+// Stops the generation in progress on whichever replica is running it, or the
+// one still queued for the conversation
+func (h *StreamHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+	conversationID := chi.URLParam(r, "id")
+
+	if err := middleware.ValidateConversationID(conversationID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Verify conversation exists and belongs to tenant
+	if _, err := h.conversationService.Get(ctx, tenantID, conversationID); err != nil {
+		writeError(w, http.StatusNotFound, "conversation not found")
+		return
+	}
+
+	cancelled, err := h.messageService.Cancel(ctx, tenantID, conversationID)
+	if err != nil {
+		h.logger.Error("failed to cancel generation", zap.Error(err), zap.String("conversation_id", conversationID))
+		writeError(w, http.StatusInternalServerError, "failed to cancel generation")
+		return
+	}
+
+	if !cancelled {
+		writeError(w, http.StatusConflict, "no generation in progress")
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]bool{"cancelled": true})
+}
+
+// tokenPosition is the attempt of a generation the client is following and
+// the next token index of it owed to the client.
+type tokenPosition struct {
+	attempt int
+	next    int
+
+	// done is set once the generation's message was sent. Relayed tokens
+	// are not ordered against the message and may still arrive after it.
+	done bool
+}
+
+// tokenCursor tracks the token position of the client per generation.
+type tokenCursor map[string]tokenPosition
+
+// complete marks a generation finished so its late tokens are dropped.
+func (c tokenCursor) complete(messageID string) {
+	c[messageID] = tokenPosition{done: true}
+}
+
+// sendFrom sends the buffered tokens of a generation the client has not seen
+// yet. Buffers of another attempt than the one followed, or of a completed
+// generation, are ignored.
+func (c tokenCursor) sendFrom(w http.ResponseWriter, flusher http.Flusher, partial *model.PartialGeneration) {
+	pos := c[partial.MessageID]
+	if pos.done || partial.Attempt != pos.attempt {
+		return
+	}
+	for ; pos.next < len(partial.Tokens); pos.next++ {
+		sendSSEEvent(w, flusher, "token", &model.TokenEvent{
+			MessageID: partial.MessageID,
+			Attempt:   partial.Attempt,
+			Token:     partial.Tokens[pos.next],
+			Index:     pos.next,
+		})
+		c[partial.MessageID] = tokenPosition{attempt: pos.attempt, next: pos.next + 1}
+	}
+}
+
+// sendLiveToken forwards a relayed token, dropping duplicates and tokens of
+// superseded attempts or completed generations, and filling any gap from the generation buffer first.
+// A token of a newer attempt restarts the generation for the client.
+func (h *StreamHandler) sendLiveToken(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, tenantID, conversationID string, tokens tokenCursor, token *model.TokenEvent) {
+	pos, seen := tokens[token.MessageID]
+	if pos.done || token.Attempt < pos.attempt {
+		return
+	}
+	if token.Attempt > pos.attempt {
+		if seen {
+			sendSSEEvent(w, flusher, "restart", &GenerationRestartEvent{MessageID: token.MessageID, Attempt: token.Attempt})
+		}
+		pos = tokenPosition{attempt: token.Attempt}
+		tokens[token.MessageID] = pos
+	}
+
+	if token.Index < pos.next {
+		return
+	}
+
+	if token.Index > pos.next {
+		partial, err := h.messageService.PartialGeneration(ctx, tenantID, conversationID, token.MessageID)
+		if err == nil && partial != nil {
+			tokens.sendFrom(w, flusher, &model.PartialGeneration{
+				MessageID: partial.MessageID,
+				Attempt:   partial.Attempt,
+				Tokens:    partial.Tokens[:min(token.Index, len(partial.Tokens))],
+			})
+			if token.Index < tokens[token.MessageID].next {
+				return
+			}
+		}
+	}
+
+	sendSSEEvent(w, flusher, "token", token)
+	tokens[token.MessageID] = tokenPosition{attempt: token.Attempt, next: token.Index + 1}
+}
+
 // StreamWithMessage handles POST /api/v1/conversations/:id/stream
 // This endpoint accepts a message and streams the response
 func (h *StreamHandler) StreamWithMessage(w http.ResponseWriter, r *http.Request) {
@@ -187,11 +386,37 @@ func (h *StreamHandler) StreamWithMessage(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := middleware.ValidateMessageContent(req.Content); err != nil {
+	if err := middleware.ValidateSendMessage(&req); err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	if err := h.messageService.ValidateModel(req.Model); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.messageService.ValidateResponseFormat(req.ResponseFormat); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.messageService.ResolveAttachments(ctx, tenantID, conversationID, &req); err != nil {
+		if !writeAttachmentError(w, err) {
+			h.logger.Error("failed to resolve attachments", zap.Error(err))
+			writeError(w, http.StatusInternalServerError, "attachment store unavailable")
+		}
+		return
+	}
+
+	// Refuse up front rather than hold a connection open for a provider that is down
+	if err := h.messageService.CheckAvailable(ctx, tenantID, conversationID, req.Model); err != nil {
+		if !writeUnavailable(w, err) {
+			writeConversationError(w, err)
+		}
+		return
+	}
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -208,33 +433,13 @@ func (h *StreamHandler) StreamWithMessage(w http.ResponseWriter, r *http.Request
 	metrics.IncrementSSEConnections()
 	defer metrics.DecrementSSEConnections()
 
-	// Send user message and stream response
-	userMsg, assistantMsg, err := h.messageService.SendWithStream(
-		ctx,
-		tenantID,
-		conversationID,
-		&req,
-		func(token string, index int) error {
-			// Check if client disconnected
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
-
-			// Send token event
-			return sendSSEEvent(w, flusher, "token", &model.TokenEvent{
-				Token: token,
-				Index: index,
-			})
-		},
-	)
-
+	// Queue the generation on a worker so it outlives this connection
+	userMsg, job, err := h.messageService.Enqueue(ctx, tenantID, conversationID, &req)
 	if err != nil {
-		// Send error event
+		h.logger.Error("failed to enqueue message", zap.Error(err), zap.String("conversation_id", conversationID))
 		sendSSEEvent(w, flusher, "error", &model.ErrorEvent{
 			Code:    "stream_error",
-			Message: err.Error(),
+			Message: "Failed to send message",
 		})
 		return
 	}
@@ -242,16 +447,103 @@ func (h *StreamHandler) StreamWithMessage(w http.ResponseWriter, r *http.Request
 	// Send user message confirmation
 	sendSSEEvent(w, flusher, "user_message", userMsg)
 
-	// Send message complete event
-	if assistantMsg != nil {
-		sendSSEEvent(w, flusher, "message_complete", &model.MessageCompleteEvent{
-			Message:  *assistantMsg,
-			Sequence: assistantMsg.Sequence,
+	// Follow the worker's output. Tokens emitted before the subscription is
+	// open are recovered from the generation buffer by sendLiveToken.
+	live, err := h.messageService.Follow(ctx, tenantID, conversationID, userMsg.Sequence)
+	if err != nil {
+		h.logger.Error("failed to follow conversation", zap.Error(err), zap.String("conversation_id", conversationID))
+		sendSSEEvent(w, flusher, "error", &model.ErrorEvent{
+			Code:    "live_error",
+			Message: "Failed to subscribe to live updates",
 		})
+		return
+	}
+
+	tokens := make(tokenCursor)
+	for {
+		select {
+		case <-ctx.Done():
+			// Client disconnected; the worker still persists the reply
+			return
+
+		case update, ok := <-live:
+			if !ok {
+				sendSSEEvent(w, flusher, "error", &model.ErrorEvent{
+					Code:    "live_error",
+					Message: "Live subscription ended",
+				})
+				return
+			}
+
+			switch {
+			case update.Token != nil && update.Token.Thinking && update.Token.MessageID == job.AssistantMessageID:
+				sendSSEEvent(w, flusher, "thinking", update.Token)
+			case update.Token != nil && update.Token.MessageID == job.AssistantMessageID:
+				h.sendLiveToken(ctx, w, flusher, tenantID, conversationID, tokens, update.Token)
+
+			case update.Message != nil && update.Message.ID == job.AssistantMessageID:
+				sendSSEEvent(w, flusher, "message_complete", &model.MessageCompleteEvent{
+					Message:  *update.Message,
+					Sequence: update.Sequence,
+					Output:   update.Message.StructuredOutput(),
+				})
+				if stop := update.Message.StopReason; stop != nil && *stop == service.StopReasonCancelled {
+					// Cut short by a cancel; the partial reply is kept
+					sendCancelledEvent(w, flusher)
+					return
+				}
+				sendSSEEvent(w, flusher, "done", map[string]bool{"success": true})
+				return
+
+			case update.Event != nil && update.Event.Type == model.EventTypeCancel:
+				// Cancelled before a worker ran it, so no reply follows
+				if update.Event.Metadata["message_id"] == job.AssistantMessageID {
+					sendCancelledEvent(w, flusher)
+					return
+				}
+
+			case update.Event != nil && update.Event.Type == model.EventTypeFailover:
+				// The worker is retrying or trying another model; keep following
+				if update.Event.Metadata["message_id"] == job.AssistantMessageID {
+					sendSSEEvent(w, flusher, "failover", update.Event)
+				}
+
+			case update.Event != nil && (update.Event.Type == model.EventTypeToolCall || update.Event.Type == model.EventTypeToolResult):
+				// Tool rounds precede the final reply; keep following
+				if update.Event.Metadata["message_id"] == job.AssistantMessageID {
+					sendSSEEvent(w, flusher, string(update.Event.Type), update.Event)
+				}
+
+			case update.Event != nil && update.Event.Type == model.EventTypeSchemaRetry:
+				// The reply is generated again; keep following
+				if update.Event.Metadata["message_id"] == job.AssistantMessageID {
+					sendSSEEvent(w, flusher, "schema_retry", update.Event)
+				}
+
+			case update.Event != nil && update.Event.Metadata["message_id"] == job.AssistantMessageID:
+				code := update.Event.Code
+				if code == "" {
+					code = "stream_error"
+				}
+				// error, rate_limit or timeout
+				sendSSEEvent(w, flusher, string(update.Event.Type), &model.ErrorEvent{
+					Code:       code,
+					Message:    update.Event.Reason,
+					RetryAfter: update.Event.RetryAfter,
+				})
+				return
+			}
+		}
 	}
+}
 
-	// Send done event
-	sendSSEEvent(w, flusher, "done", map[string]bool{"success": true})
+// sendCancelledEvent ends a stream whose generation was cancelled by request,
+// so clients can tell it from a failure.
+func sendCancelledEvent(w http.ResponseWriter, flusher http.Flusher) {
+	sendSSEEvent(w, flusher, "cancelled", &model.ErrorEvent{
+		Code:    service.StopReasonCancelled,
+		Message: "Generation cancelled by request",
+	})
 }
 
 func sendSSEEvent(w http.ResponseWriter, flusher http.Flusher, event string, data interface{}) error {
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/capitalize-ai/conversational-platform/internal/middleware"
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+	"github.com/capitalize-ai/conversational-platform/internal/service"
+	"github.com/capitalize-ai/conversational-platform/pkg/logger"
+)
+
+// TemplateHandler handles prompt template endpoints.
+type TemplateHandler struct {
+	service *service.TemplateService
+	logger  *logger.Logger
+}
+
+// NewTemplateHandler creates a new template handler.
+func NewTemplateHandler(svc *service.TemplateService, log *logger.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		service: svc,
+		logger:  log,
+	}
+}
+
+// Create handles POST /api/v1/templates
+func (h *TemplateHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+
+	var req model.CreateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := middleware.ValidateTemplateName(req.Name); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := middleware.ValidateSystemPrompt(req.Content); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tpl, err := h.service.Create(ctx, tenantID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTemplate) {
+			writeTemplateError(w, err)
+			return
+		}
+		h.logger.Error("failed to create template", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to create template")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, tpl)
+}
+
+// List handles GET /api/v1/templates
+func (h *TemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+
+	resp, err := h.service.List(ctx, tenantID)
+	if err != nil {
+		h.logger.Error("failed to list templates", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to list templates")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Get handles GET /api/v1/templates/:id and GET /api/v1/templates/:id/versions/:version
+func (h *TemplateHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+	templateID := chi.URLParam(r, "id")
+
+	if err := middleware.ValidateTemplateID(templateID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	version := 0
+	if v := chi.URLParam(r, "version"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, "invalid template version")
+			return
+		}
+		version = parsed
+	}
+
+	tpl, err := h.service.Get(ctx, tenantID, templateID, version)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tpl)
+}
+
+// Update handles PUT /api/v1/templates/:id
+func (h *TemplateHandler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+	templateID := chi.URLParam(r, "id")
+
+	if err := middleware.ValidateTemplateID(templateID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req model.UpdateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Name != "" {
+		if err := middleware.ValidateTemplateName(req.Name); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if err := middleware.ValidateSystemPrompt(req.Content); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tpl, err := h.service.Update(ctx, tenantID, templateID, &req)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tpl)
+}
+
+// Delete handles DELETE /api/v1/templates/:id
+func (h *TemplateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+	templateID := chi.URLParam(r, "id")
+
+	if err := middleware.ValidateTemplateID(templateID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.Delete(ctx, tenantID, templateID); err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeTemplateError maps template service errors to HTTP responses.
+func writeTemplateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrTemplateNotFound):
+		writeError(w, http.StatusNotFound, "template not found")
+	case errors.Is(err, service.ErrTemplateConflict):
+		writeError(w, http.StatusConflict, "template was modified concurrently")
+	case errors.Is(err, service.ErrInvalidTemplate):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "template store unavailable")
+	}
+}
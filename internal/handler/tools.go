@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/capitalize-ai/conversational-platform/internal/middleware"
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+	"github.com/capitalize-ai/conversational-platform/internal/service"
+	"github.com/capitalize-ai/conversational-platform/pkg/logger"
+)
+
+// ToolHandler handles tool definition endpoints.
+type ToolHandler struct {
+	service *service.ToolService
+	logger  *logger.Logger
+}
+
+// NewToolHandler creates a new tool handler.
+func NewToolHandler(svc *service.ToolService, log *logger.Logger) *ToolHandler {
+	return &ToolHandler{
+		service: svc,
+		logger:  log,
+	}
+}
+
+// Create handles POST /api/v1/tools
+func (h *ToolHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+
+	var req model.CreateToolRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := middleware.ValidateToolName(req.Name); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := middleware.ValidateToolDescription(req.Description); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := middleware.ValidateWebhookURL(req.WebhookURL); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tool, err := h.service.Create(ctx, tenantID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTool) || errors.Is(err, service.ErrToolExists) {
+			writeToolError(w, err)
+			return
+		}
+		h.logger.Error("failed to create tool", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to create tool")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, redactTool(tool))
+}
+
+// List handles GET /api/v1/tools
+func (h *ToolHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+
+	resp, err := h.service.List(ctx, tenantID)
+	if err != nil {
+		h.logger.Error("failed to list tools", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to list tools")
+		return
+	}
+
+	for i := range resp.Tools {
+		redactTool(&resp.Tools[i])
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Get handles GET /api/v1/tools/:name
+func (h *ToolHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+	name := chi.URLParam(r, "name")
+
+	if err := middleware.ValidateToolName(name); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tool, err := h.service.Get(ctx, tenantID, name)
+	if err != nil {
+		writeToolError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, redactTool(tool))
+}
+
+// Update handles PUT /api/v1/tools/:name
+func (h *ToolHandler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+	name := chi.URLParam(r, "name")
+
+	if err := middleware.ValidateToolName(name); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req model.UpdateToolRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := middleware.ValidateToolDescription(req.Description); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.WebhookURL != "" {
+		if err := middleware.ValidateWebhookURL(req.WebhookURL); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	tool, err := h.service.Update(ctx, tenantID, name, &req)
+	if err != nil {
+		writeToolError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, redactTool(tool))
+}
+
+// Delete handles DELETE /api/v1/tools/:name
+func (h *ToolHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := middleware.GetTenantID(ctx)
+	name := chi.URLParam(r, "name")
+
+	if err := middleware.ValidateToolName(name); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.Delete(ctx, tenantID, name); err != nil {
+		writeToolError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// redactTool hides a tool's webhook signing secret from API responses.
+func redactTool(tool *model.Tool) *model.Tool {
+	tool.Secret = ""
+	return tool
+}
+
+// writeToolError maps tool service errors to HTTP responses.
+func writeToolError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrToolNotFound):
+		writeError(w, http.StatusNotFound, "tool not found")
+	case errors.Is(err, service.ErrToolExists):
+		writeError(w, http.StatusConflict, "tool already exists")
+	case errors.Is(err, service.ErrToolConflict):
+		writeError(w, http.StatusConflict, "tool was modified concurrently")
+	case errors.Is(err, service.ErrInvalidTool):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "tool store unavailable")
+	}
+}
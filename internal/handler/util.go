@@ -2,7 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+
+	"github.com/capitalize-ai/conversational-platform/internal/llm"
+	"github.com/capitalize-ai/conversational-platform/internal/model"
 )
 
 // writeJSON writes a JSON response.
@@ -18,3 +24,21 @@ func writeError(w http.ResponseWriter, status int, message string) {
 		"error": message,
 	})
 }
+
+// writeUnavailable writes a 503 ErrorEvent if err is an open circuit breaker,
+// reporting whether it did.
+func writeUnavailable(w http.ResponseWriter, err error) bool {
+	var open *llm.BreakerOpenError
+	if !errors.As(err, &open) {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(open.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeJSON(w, http.StatusServiceUnavailable, &model.ErrorEvent{
+		Code:       "provider_unavailable",
+		Message:    "LLM provider is temporarily unavailable",
+		RetryAfter: retryAfter,
+	})
+	return true
+}
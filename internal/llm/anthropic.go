@@ -2,7 +2,11 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -37,6 +41,7 @@ func (c *AnthropicClient) Name() string {
 // Models returns available models.
 func (c *AnthropicClient) Models() []string {
 	return []string{
+		"claude-3-7-sonnet-20250219",
 		"claude-3-5-sonnet-20241022",
 		"claude-3-5-haiku-20241022",
 		"claude-3-opus-20240229",
@@ -49,6 +54,92 @@ func (c *AnthropicClient) Models() []string {
 func (c *AnthropicClient) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
 	start := time.Now()
 
+	params, _ := c.newParams(req)
+
+	resp, err := c.client.Messages.New(ctx, params)
+	if err != nil {
+		return nil, c.wrapError(ctx, err)
+	}
+
+	// Extract content
+	var content string
+	var toolCalls []ToolCall
+	var thinking []ContentBlock
+	var answer string
+	for _, block := range resp.Content {
+		switch block.Type {
+		case anthropic.ContentBlockTypeText:
+			content += block.Text
+		case anthropic.ContentBlockTypeToolUse:
+			if isAnswerTool(req, block.Name) {
+				answer = string(block.Input)
+				continue
+			}
+			toolCalls = append(toolCalls, ToolCall{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: json.RawMessage(block.Input),
+			})
+		case anthropic.ContentBlockTypeThinking:
+			thinking = append(thinking, ContentBlock{Type: BlockThinking, Thinking: block.Thinking, Signature: block.Signature})
+		case anthropic.ContentBlockTypeRedactedThinking:
+			thinking = append(thinking, ContentBlock{Type: BlockRedactedThinking, Data: block.Data})
+		}
+	}
+
+	completion := c.newResponse(resp, content, start)
+	completion.ToolCalls = toolCalls
+	completion.Thinking = thinking
+	if answer != "" {
+		setAnswer(completion, answer)
+	}
+	return completion, nil
+}
+
+// Validate checks a request against Anthropic's limits.
+func (c *AnthropicClient) Validate(req *CompletionRequest) error {
+	if err := checkModel(c, req.Model); err != nil {
+		return err
+	}
+	if err := checkRange("temperature", req.Temperature, 0, 1); err != nil {
+		return err
+	}
+	if err := checkRange("top_p", req.TopP, 0, 1); err != nil {
+		return err
+	}
+	if err := checkMaxTokens(req.MaxTokens, anthropicMaxOutput(req.Model)); err != nil {
+		return err
+	}
+	if req.ThinkingBudget != 0 {
+		if !strings.HasPrefix(req.Model, "claude-3-7-") {
+			return fmt.Errorf("%w: extended thinking requires claude-3-7-sonnet or later", ErrInvalidRequest)
+		}
+		// Sampling is fixed while the model thinks
+		if req.Temperature != nil || req.TopP != nil {
+			return fmt.Errorf("%w: temperature and top_p cannot be set with extended thinking", ErrInvalidRequest)
+		}
+		if err := checkThinkingBudget(req.ThinkingBudget, req.MaxTokens); err != nil {
+			return err
+		}
+	}
+	return checkStopSequences(req.StopSequences, 0)
+}
+
+// anthropicMaxOutput returns the largest completion a Claude model can produce.
+func anthropicMaxOutput(model string) int {
+	switch {
+	case strings.HasPrefix(model, "claude-3-7-"):
+		return 64000
+	case strings.HasPrefix(model, "claude-3-5-") || model == "":
+		return 8192
+	}
+	return 4096
+}
+
+// newParams converts a request to Anthropic's format and returns it with the
+// model it targets. System messages are moved to the top-level system
+// parameter, which is the only place the Messages API accepts them.
+func (c *AnthropicClient) newParams(req *CompletionRequest) (anthropic.MessageNewParams, string) {
 	model := req.Model
 	if model == "" {
 		model = "claude-3-5-sonnet-20241022"
@@ -59,119 +150,355 @@ func (c *AnthropicClient) Complete(ctx context.Context, req *CompletionRequest)
 		maxTokens = 4096
 	}
 
-	// Convert messages to Anthropic format
-	messages := make([]anthropic.MessageParam, len(req.Messages))
-	for i, msg := range req.Messages {
-		messages[i] = anthropic.MessageParam{
-			Role: anthropic.F(anthropic.MessageParamRole(msg.Role)),
-			Content: anthropic.F([]anthropic.ContentBlockParamUnion{
-				anthropic.TextBlockParam{
-					Type: anthropic.F(anthropic.TextBlockParamTypeText),
-					Text: anthropic.F(msg.Content),
-				},
-			}),
+	var system []anthropic.TextBlockParam
+	if req.System != "" {
+		system = append(system, anthropicText(req.System))
+	}
+
+	// Convert messages to Anthropic format. Tool results travel in user
+	// turns, so consecutive turns of one role are merged to keep the roles
+	// alternating.
+	var roles []anthropic.MessageParamRole
+	var turns [][]anthropic.ContentBlockParamUnion
+	for _, msg := range req.Messages {
+		if msg.Role == "system" {
+			system = append(system, anthropicText(msg.Content))
+			continue
+		}
+
+		role, blocks := anthropicContent(msg)
+		if n := len(roles); n > 0 && roles[n-1] == role {
+			turns[n-1] = append(turns[n-1], blocks...)
+			continue
 		}
+		roles = append(roles, role)
+		turns = append(turns, blocks)
 	}
 
-	resp, err := c.client.Messages.New(ctx, anthropic.MessageNewParams{
+	messages := make([]anthropic.MessageParam, 0, len(turns))
+	for i, blocks := range turns {
+		messages = append(messages, anthropic.MessageParam{
+			Role:    anthropic.F(roles[i]),
+			Content: anthropic.F(blocks),
+		})
+	}
+
+	params := anthropic.MessageNewParams{
 		Model:     anthropic.F(model),
 		MaxTokens: anthropic.F(int64(maxTokens)),
 		Messages:  anthropic.F(messages),
-	})
-	if err != nil {
-		return nil, err
+	}
+	if len(system) > 0 {
+		params.System = anthropic.F(system)
+	}
+	if req.Temperature != nil {
+		params.Temperature = anthropic.F(*req.Temperature)
+	}
+	if req.TopP != nil {
+		params.TopP = anthropic.F(*req.TopP)
+	}
+	if len(req.StopSequences) > 0 {
+		params.StopSequences = anthropic.F(req.StopSequences)
 	}
 
-	// Extract content
-	var content string
-	for _, block := range resp.Content {
-		if block.Type == anthropic.ContentBlockTypeText {
-			content += block.Text
+	tools := make([]anthropic.ToolUnionUnionParam, 0, len(req.Tools)+1)
+	for _, tool := range req.Tools {
+		if isAnswerTool(req, tool.Name) {
+			// The answer tool takes the name
+			continue
+		}
+		tools = append(tools, anthropicTool(tool))
+	}
+	if format := req.ResponseFormat; format != nil {
+		// Claude has no JSON mode, so it answers by calling a tool whose
+		// input schema is the format's
+		tools = append(tools, anthropicTool(Tool{
+			Name:        format.Name,
+			Description: "Give your final answer by calling this tool with it.",
+			InputSchema: format.Schema,
+		}))
+		switch {
+		case req.ThinkingBudget > 0:
+			// Thinking only allows the model to choose its tools; an answer
+			// given as text fails validation instead
+		case len(tools) > 1:
+			// Other tools may be called first
+			params.ToolChoice = anthropic.F[anthropic.ToolChoiceUnionParam](anthropic.ToolChoiceAnyParam{
+				Type: anthropic.F(anthropic.ToolChoiceAnyTypeAny),
+			})
+		default:
+			params.ToolChoice = anthropic.F[anthropic.ToolChoiceUnionParam](anthropic.ToolChoiceToolParam{
+				Type: anthropic.F(anthropic.ToolChoiceToolTypeTool),
+				Name: anthropic.F(format.Name),
+			})
 		}
 	}
+	if len(tools) > 0 {
+		params.Tools = anthropic.F(tools)
+	}
+	if req.ThinkingBudget > 0 {
+		params.Thinking = anthropic.F[anthropic.ThinkingConfigParamUnion](anthropic.ThinkingConfigEnabledParam{
+			Type:         anthropic.F(anthropic.ThinkingConfigEnabledTypeEnabled),
+			BudgetTokens: anthropic.F(int64(req.ThinkingBudget)),
+		})
+	}
 
-	return &CompletionResponse{
-		Content:    content,
-		Model:      resp.Model,
-		TokensIn:   int(resp.Usage.InputTokens),
-		TokensOut:  int(resp.Usage.OutputTokens),
-		StopReason: string(resp.StopReason),
-		LatencyMs:  time.Since(start).Milliseconds(),
-	}, nil
+	return params, model
 }
 
-// CompleteStream sends a streaming completion request.
-func (c *AnthropicClient) CompleteStream(ctx context.Context, req *CompletionRequest, callback StreamCallback) (*CompletionResponse, error) {
-	start := time.Now()
+// anthropicTool converts a tool declaration to Anthropic's format.
+func anthropicTool(tool Tool) anthropic.ToolParam {
+	return anthropic.ToolParam{
+		Name:        anthropic.F(tool.Name),
+		Description: anthropic.F(tool.Description),
+		InputSchema: anthropic.F[interface{}](tool.InputSchema),
+	}
+}
 
-	model := req.Model
-	if model == "" {
-		model = "claude-3-5-sonnet-20241022"
+// isAnswerTool reports whether a tool is the one Claude gives a formatted
+// answer through.
+func isAnswerTool(req *CompletionRequest, name string) bool {
+	return req.ResponseFormat != nil && req.ResponseFormat.Name == name
+}
+
+// setAnswer makes the input of the answer tool a response's content. The
+// call ended the turn, so it is reported as such.
+func setAnswer(resp *CompletionResponse, answer string) {
+	resp.Content = answer
+	if len(resp.ToolCalls) == 0 {
+		resp.StopReason = string(anthropic.MessageStopReasonEndTurn)
 	}
+}
 
-	maxTokens := req.MaxTokens
-	if maxTokens == 0 {
-		maxTokens = 4096
+// anthropicContent converts a message to the role and content blocks of an
+// Anthropic turn. Tool results travel in user turns.
+func anthropicContent(msg ChatMessage) (anthropic.MessageParamRole, []anthropic.ContentBlockParamUnion) {
+	role := anthropic.MessageParamRole(msg.Role)
+	if msg.Role == "tool" {
+		role = anthropic.MessageParamRoleUser
 	}
 
-	// Convert messages to Anthropic format
-	messages := make([]anthropic.MessageParam, len(req.Messages))
-	for i, msg := range req.Messages {
-		messages[i] = anthropic.MessageParam{
-			Role: anthropic.F(anthropic.MessageParamRole(msg.Role)),
-			Content: anthropic.F([]anthropic.ContentBlockParamUnion{
-				anthropic.TextBlockParam{
-					Type: anthropic.F(anthropic.TextBlockParamTypeText),
-					Text: anthropic.F(msg.Content),
-				},
-			}),
+	content := msg.ContentBlocks()
+	blocks := make([]anthropic.ContentBlockParamUnion, 0, len(content))
+	for _, block := range content {
+		switch block.Type {
+		case BlockText:
+			// Empty text blocks are rejected beside other blocks
+			if block.Text == "" && len(content) > 1 {
+				continue
+			}
+			blocks = append(blocks, anthropicText(block.Text))
+		case BlockImage:
+			blocks = append(blocks, anthropicImage(block.Image))
+		case BlockToolUse:
+			input := block.ToolCall.Arguments
+			if len(input) == 0 {
+				input = json.RawMessage("{}")
+			}
+			blocks = append(blocks, anthropic.ToolUseBlockParam{
+				Type:  anthropic.F(anthropic.ToolUseBlockParamTypeToolUse),
+				ID:    anthropic.F(block.ToolCall.ID),
+				Name:  anthropic.F(block.ToolCall.Name),
+				Input: anthropic.F[interface{}](input),
+			})
+		case BlockToolResult:
+			var content []anthropic.ToolResultBlockParamContentUnion
+			for _, part := range block.Content {
+				switch part.Type {
+				case BlockText:
+					content = append(content, anthropicText(part.Text))
+				case BlockImage:
+					content = append(content, anthropicImage(part.Image))
+				}
+			}
+			result := anthropic.ToolResultBlockParam{
+				Type:      anthropic.F(anthropic.ToolResultBlockParamTypeToolResult),
+				ToolUseID: anthropic.F(block.ToolCallID),
+				Content:   anthropic.F(content),
+			}
+			if block.IsError {
+				result.IsError = anthropic.F(true)
+			}
+			blocks = append(blocks, result)
+		case BlockThinking:
+			blocks = append(blocks, anthropic.ThinkingBlockParam{
+				Type:      anthropic.F(anthropic.ThinkingBlockParamTypeThinking),
+				Thinking:  anthropic.F(block.Thinking),
+				Signature: anthropic.F(block.Signature),
+			})
+		case BlockRedactedThinking:
+			blocks = append(blocks, anthropic.RedactedThinkingBlockParam{
+				Type: anthropic.F(anthropic.RedactedThinkingBlockParamTypeRedactedThinking),
+				Data: anthropic.F(block.Data),
+			})
 		}
 	}
 
-	stream := c.client.Messages.NewStreaming(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.F(model),
-		MaxTokens: anthropic.F(int64(maxTokens)),
-		Messages:  anthropic.F(messages),
-	})
+	return role, blocks
+}
+
+// anthropicText converts text to an Anthropic text block.
+func anthropicText(text string) anthropic.TextBlockParam {
+	return anthropic.TextBlockParam{
+		Type: anthropic.F(anthropic.TextBlockParamTypeText),
+		Text: anthropic.F(text),
+	}
+}
+
+// anthropicImage converts an image to an Anthropic image block.
+func anthropicImage(image *Image) anthropic.ImageBlockParam {
+	return anthropic.ImageBlockParam{
+		Type: anthropic.F(anthropic.ImageBlockParamTypeImage),
+		Source: anthropic.F[anthropic.ImageBlockParamSourceUnion](anthropic.Base64ImageSourceParam{
+			Type:      anthropic.F(anthropic.Base64ImageSourceTypeBase64),
+			MediaType: anthropic.F(anthropic.Base64ImageSourceMediaType(image.MediaType)),
+			Data:      anthropic.F(image.Data),
+		}),
+	}
+}
+
+// CompleteStream sends a streaming completion request.
+func (c *AnthropicClient) CompleteStream(ctx context.Context, req *CompletionRequest, callback StreamCallback) (*CompletionResponse, error) {
+	start := time.Now()
 
+	params, model := c.newParams(req)
+
+	stream := c.client.Messages.NewStreaming(ctx, params)
+
+	// Rebuild the message from its events: message_start carries the served
+	// model and input usage, message_delta the stop reason and output usage.
+	message := anthropic.Message{Model: model}
 	var content string
-	var tokensIn, tokensOut int
-	var stopReason string
 	index := 0
 
-	message := stream.Current()
+	// Tool call arguments arrive as JSON fragments keyed by content block
+	var toolCalls []ToolCall
+	var toolInputs []string
+	toolBlocks := make(map[int64]int)
 
-	for stream.Next() {
-		event := stream.Current()
+	// A formatted answer is streamed as it is written, like text
+	var answer string
+	answerBlock := int64(-1)
+
+	// Thinking arrives as deltas followed by the block's signature
+	var thinking []ContentBlock
+	thinkingBlocks := make(map[int64]int)
 
-		switch event.Type {
-		case anthropic.MessageStreamEventTypeContentBlockDelta:
-			if event.Delta.Type == "text_delta" {
+	for stream.Next() {
+		switch event := stream.Current().AsUnion().(type) {
+		case anthropic.MessageStartEvent:
+			message = event.Message
+			if message.Model == "" {
+				message.Model = model
+			}
+		case anthropic.ContentBlockStartEvent:
+			switch event.ContentBlock.Type {
+			case anthropic.ContentBlockStartEventContentBlockTypeToolUse:
+				if isAnswerTool(req, event.ContentBlock.Name) {
+					answerBlock = event.Index
+					break
+				}
+				toolBlocks[event.Index] = len(toolCalls)
+				toolCalls = append(toolCalls, ToolCall{ID: event.ContentBlock.ID, Name: event.ContentBlock.Name})
+				toolInputs = append(toolInputs, "")
+			case anthropic.ContentBlockStartEventContentBlockTypeThinking:
+				thinkingBlocks[event.Index] = len(thinking)
+				thinking = append(thinking, ContentBlock{Type: BlockThinking})
+			case anthropic.ContentBlockStartEventContentBlockTypeRedactedThinking:
+				thinking = append(thinking, ContentBlock{Type: BlockRedactedThinking, Data: event.ContentBlock.Data})
+			}
+		case anthropic.ContentBlockDeltaEvent:
+			switch event.Delta.Type {
+			case anthropic.ContentBlockDeltaEventDeltaTypeTextDelta:
 				token := event.Delta.Text
 				content += token
 				if err := callback(token, index); err != nil {
 					return nil, err
 				}
 				index++
+			case anthropic.ContentBlockDeltaEventDeltaTypeInputJSONDelta:
+				if event.Index == answerBlock {
+					token := event.Delta.PartialJSON
+					answer += token
+					if err := callback(token, index); err != nil {
+						return nil, err
+					}
+					index++
+				}
+				if i, ok := toolBlocks[event.Index]; ok {
+					toolInputs[i] += event.Delta.PartialJSON
+				}
+			case anthropic.ContentBlockDeltaEventDeltaTypeThinkingDelta:
+				if i, ok := thinkingBlocks[event.Index]; ok {
+					thinking[i].Thinking += event.Delta.Thinking
+				}
+				if req.OnThinking != nil {
+					if err := req.OnThinking(event.Delta.Thinking); err != nil {
+						return nil, err
+					}
+				}
+			case anthropic.ContentBlockDeltaEventDeltaTypeSignatureDelta:
+				if i, ok := thinkingBlocks[event.Index]; ok {
+					thinking[i].Signature += event.Delta.Signature
+				}
 			}
-		case anthropic.MessageStreamEventTypeMessageDelta:
-			stopReason = string(event.Delta.StopReason)
-			tokensOut = int(event.Usage.OutputTokens)
+		case anthropic.MessageDeltaEvent:
+			message.StopReason = anthropic.MessageStopReason(event.Delta.StopReason)
+			message.Usage.OutputTokens = event.Usage.OutputTokens
 		}
 	}
 
 	if err := stream.Err(); err != nil {
-		return nil, err
+		return nil, c.wrapError(ctx, err)
 	}
 
-	tokensIn = int(message.Usage.InputTokens)
+	for i := range toolCalls {
+		toolCalls[i].Arguments = json.RawMessage(toolInputs[i])
+		if len(toolCalls[i].Arguments) == 0 {
+			toolCalls[i].Arguments = json.RawMessage("{}")
+		}
+	}
+
+	resp := c.newResponse(&message, content, start)
+	resp.ToolCalls = toolCalls
+	resp.Thinking = thinking
+	if answerBlock >= 0 {
+		setAnswer(resp, answer)
+	}
+	return resp, nil
+}
 
+// newResponse converts an Anthropic message and its text to a
+// CompletionResponse.
+func (c *AnthropicClient) newResponse(msg *anthropic.Message, content string, start time.Time) *CompletionResponse {
 	return &CompletionResponse{
-		Content:    content,
-		Model:      model,
-		TokensIn:   tokensIn,
-		TokensOut:  tokensOut,
-		StopReason: stopReason,
-		LatencyMs:  time.Since(start).Milliseconds(),
-	}, nil
+		Content:          content,
+		Provider:         c.Name(),
+		Model:            msg.Model,
+		TokensIn:         int(msg.Usage.InputTokens),
+		TokensOut:        int(msg.Usage.OutputTokens),
+		StopReason:       string(msg.StopReason),
+		LatencyMs:        time.Since(start).Milliseconds(),
+		CacheReadTokens:  int(msg.Usage.CacheReadInputTokens),
+		CacheWriteTokens: int(msg.Usage.CacheCreationInputTokens),
+	}
+}
+
+// wrapError classifies an Anthropic API failure.
+func (c *AnthropicClient) wrapError(ctx context.Context, err error) error {
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		var header http.Header
+		if apiErr.Response != nil {
+			header = apiErr.Response.Header
+		}
+		return classifyError(ctx, c.Name(), err, apiErr.StatusCode, header)
+	}
+
+	// Errors sent mid-stream arrive as SSE events without an HTTP status
+	if strings.Contains(err.Error(), "overloaded_error") {
+		return classifyError(ctx, c.Name(), err, 529, nil)
+	}
+
+	return classifyError(ctx, c.Name(), err, 0, nil)
 }
@@ -0,0 +1,311 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a model's circuit breaker rejects a request.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every request through.
+	BreakerClosed BreakerState = iota
+
+	// BreakerHalfOpen lets a few probe requests through to test recovery.
+	BreakerHalfOpen
+
+	// BreakerOpen rejects every request until its cooldown elapses.
+	BreakerOpen
+)
+
+// String returns the state name.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half_open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerOpenError is returned by a breaker that rejects a request. It
+// matches ErrCircuitOpen with errors.Is.
+type BreakerOpenError struct {
+	Provider   string
+	Model      string
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *BreakerOpenError) Error() string {
+	return fmt.Sprintf("%s/%s: %v, retry in %s", e.Provider, e.Model, ErrCircuitOpen, e.RetryAfter.Round(time.Second))
+}
+
+// Is reports whether target is ErrCircuitOpen.
+func (e *BreakerOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// Checker is implemented by clients that can tell up front whether a request
+// would be turned away by open circuit breakers.
+type Checker interface {
+	// Check returns an error matching ErrCircuitOpen if the breaker of the
+	// request's model and of every fallback is open.
+	Check(req *CompletionRequest) error
+}
+
+// BreakerConfig configures the circuit breakers of a Breakers set.
+type BreakerConfig struct {
+	// Window is the period over which outcomes are counted.
+	Window time.Duration
+
+	// MinRequests is the number of outcomes in a window before the error
+	// rate is acted on.
+	MinRequests int
+
+	// ErrorRate is the failure ratio, between 0 and 1, that opens the breaker.
+	ErrorRate float64
+
+	// SlowThreshold counts successful calls slower than this as failures.
+	// For streams the latency is the time to the first token. Zero disables it.
+	SlowThreshold time.Duration
+
+	// OpenDuration is how long an open breaker rejects requests before probing.
+	OpenDuration time.Duration
+
+	// HalfOpenProbes is the number of successful probes that close the breaker.
+	HalfOpenProbes int
+}
+
+// BreakerStatus is a point-in-time view of one breaker.
+type BreakerStatus struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	State    string `json:"state"`
+}
+
+// Breakers holds one circuit breaker per provider and model.
+type Breakers struct {
+	config BreakerConfig
+
+	// OnStateChange, if set, is called on every state transition, outside
+	// the breakers' lock.
+	OnStateChange func(provider, model string, state BreakerState)
+
+	mu       sync.Mutex
+	breakers map[breakerKey]*breaker
+}
+
+type breakerKey struct {
+	provider string
+	model    string
+}
+
+// stateChange is a transition to report once the lock is released.
+type stateChange struct {
+	key   breakerKey
+	state BreakerState
+}
+
+// breaker tracks the outcomes of one provider and model.
+type breaker struct {
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int // in flight while half-open
+	successes   int // while half-open
+}
+
+// NewBreakers creates an empty set of circuit breakers.
+func NewBreakers(config BreakerConfig) *Breakers {
+	if config.HalfOpenProbes < 1 {
+		config.HalfOpenProbes = 1
+	}
+	return &Breakers{
+		config:   config,
+		breakers: make(map[breakerKey]*breaker),
+	}
+}
+
+// Allow reports whether a request to a model may proceed. If it may, the
+// caller must report the outcome with the returned function.
+func (b *Breakers) Allow(provider, model string) (func(err error, latency time.Duration), error) {
+	key := breakerKey{provider, model}
+
+	b.mu.Lock()
+	change, err := b.admit(key)
+	b.mu.Unlock()
+
+	b.notify(change)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(err error, latency time.Duration) {
+		b.record(key, err, latency)
+	}, nil
+}
+
+// admit lets a request through a breaker or returns a BreakerOpenError.
+// b.mu must be held.
+func (b *Breakers) admit(key breakerKey) (*stateChange, error) {
+	br := b.get(key)
+
+	var change *stateChange
+	switch br.state {
+	case BreakerOpen:
+		wait := b.config.OpenDuration - time.Since(br.openedAt)
+		if wait > 0 {
+			return nil, &BreakerOpenError{Provider: key.provider, Model: key.model, RetryAfter: wait}
+		}
+		change = b.transition(key, br, BreakerHalfOpen)
+		fallthrough
+	case BreakerHalfOpen:
+		if br.probes >= b.config.HalfOpenProbes {
+			return change, &BreakerOpenError{Provider: key.provider, Model: key.model, RetryAfter: time.Second}
+		}
+		br.probes++
+	}
+
+	return change, nil
+}
+
+// Check returns the error Allow would return for a model, without counting
+// a request against it.
+func (b *Breakers) Check(provider, model string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.breakers[breakerKey{provider, model}]
+	if !ok || br.state != BreakerOpen {
+		return nil
+	}
+
+	wait := b.config.OpenDuration - time.Since(br.openedAt)
+	if wait <= 0 {
+		return nil
+	}
+	return &BreakerOpenError{Provider: provider, Model: model, RetryAfter: wait}
+}
+
+// Status returns the state of every breaker that has seen traffic.
+func (b *Breakers) Status() []BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(b.breakers))
+	for key, br := range b.breakers {
+		statuses = append(statuses, BreakerStatus{
+			Provider: key.provider,
+			Model:    key.model,
+			State:    br.state.String(),
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Provider != statuses[j].Provider {
+			return statuses[i].Provider < statuses[j].Provider
+		}
+		return statuses[i].Model < statuses[j].Model
+	})
+
+	return statuses
+}
+
+// record applies the outcome of an allowed request.
+func (b *Breakers) record(key breakerKey, err error, latency time.Duration) {
+	b.mu.Lock()
+	change := b.apply(key, err, latency)
+	b.mu.Unlock()
+
+	b.notify(change)
+}
+
+// apply counts an outcome against a breaker. b.mu must be held.
+func (b *Breakers) apply(key breakerKey, err error, latency time.Duration) *stateChange {
+	br := b.get(key)
+
+	// Callers giving up or running out of time say nothing about the provider
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		if br.state == BreakerHalfOpen {
+			br.probes--
+		}
+		return nil
+	}
+
+	failed := isRetryable(err) ||
+		(err == nil && b.config.SlowThreshold > 0 && latency > b.config.SlowThreshold)
+
+	switch br.state {
+	case BreakerHalfOpen:
+		br.probes--
+		if failed {
+			return b.open(key, br)
+		}
+		br.successes++
+		if br.successes >= b.config.HalfOpenProbes {
+			return b.transition(key, br, BreakerClosed)
+		}
+
+	case BreakerClosed:
+		now := time.Now()
+		if now.Sub(br.windowStart) > b.config.Window {
+			br.windowStart, br.requests, br.failures = now, 0, 0
+		}
+		br.requests++
+		if failed {
+			br.failures++
+		}
+		if br.requests >= b.config.MinRequests &&
+			float64(br.failures)/float64(br.requests) >= b.config.ErrorRate {
+			return b.open(key, br)
+		}
+	}
+
+	return nil
+}
+
+func (b *Breakers) get(key breakerKey) *breaker {
+	br, ok := b.breakers[key]
+	if !ok {
+		br = &breaker{windowStart: time.Now()}
+		b.breakers[key] = br
+	}
+	return br
+}
+
+func (b *Breakers) open(key breakerKey, br *breaker) *stateChange {
+	br.openedAt = time.Now()
+	return b.transition(key, br, BreakerOpen)
+}
+
+// transition moves a breaker to a new state and resets its counters. The
+// change is returned for the caller to report after unlocking.
+func (b *Breakers) transition(key breakerKey, br *breaker, state BreakerState) *stateChange {
+	br.state = state
+	br.windowStart = time.Now()
+	br.requests, br.failures = 0, 0
+	br.probes, br.successes = 0, 0
+
+	return &stateChange{key: key, state: state}
+}
+
+// notify reports a state change to OnStateChange. b.mu must not be held, so
+// the callback may call back into the breakers.
+func (b *Breakers) notify(change *stateChange) {
+	if change != nil && b.OnStateChange != nil {
+		b.OnStateChange(change.key.provider, change.key.model, change.state)
+	}
+}
@@ -3,34 +3,112 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
 )
 
 // StreamCallback is called for each token during streaming.
 type StreamCallback func(token string, index int) error
 
-// CompletionRequest represents a completion request.
+// CompletionRequest represents a completion request. Optional sampling
+// settings are nil or empty when the provider default should apply.
 type CompletionRequest struct {
-	Model       string
-	Messages    []ChatMessage
-	MaxTokens   int
-	Temperature float64
-	Stream      bool
+	Model         string
+	System        string
+	Messages      []ChatMessage
+	MaxTokens     int
+	Temperature   *float64
+	TopP          *float64
+	StopSequences []string
+	Stream        bool
+
+	// Tools the model may call instead of, or before, answering.
+	Tools []Tool
+
+	// ThinkingBudget is the number of tokens, out of MaxTokens, the model may
+	// spend reasoning before it answers. Zero disables extended thinking.
+	ThinkingBudget int
+
+	// ResponseFormat, if set, asks for the answer as a JSON document matching
+	// a schema, returned as the response's Content. Providers constrain the
+	// output as far as they can but do not guarantee it matches.
+	ResponseFormat *ResponseFormat
+
+	// OnThinking, if set, receives reasoning deltas while streaming. They
+	// are not passed to the StreamCallback.
+	OnThinking func(delta string) error
+
+	// Fallbacks lists models to try, in order, if Model fails. Only clients
+	// wrapped in a FailoverClient honour it.
+	Fallbacks []string
+
+	// OnFailover, if set, is told about every failed attempt that is retried
+	// or failed over.
+	OnFailover func(Failover)
 }
 
-// ChatMessage represents a chat message for LLM.
+// ChatMessage represents a chat message for LLM. Assistant messages may carry
+// tool calls; the results come back in "tool" messages naming the call.
+// Blocks, when set, is the full typed content, including images, and takes
+// precedence over Content and the tool call fields.
 type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string         `json:"role"`
+	Content    string         `json:"content"`
+	ToolCalls  []ToolCall     `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	IsError    bool           `json:"is_error,omitempty"`
+	Blocks     []ContentBlock `json:"blocks,omitempty"`
+}
+
+// Tool declares a function the model may call. InputSchema is the JSON
+// Schema of its arguments.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// ResponseFormat names a JSON Schema the answer must match. The schema's
+// root is an object.
+type ResponseFormat struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// ToolCall is a call the model made to a declared tool.
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
 }
 
 // CompletionResponse represents a completion response.
 type CompletionResponse struct {
 	Content    string
+	Provider   string
 	Model      string
 	TokensIn   int
 	TokensOut  int
 	StopReason string
 	LatencyMs  int64
+
+	// CacheReadTokens and CacheWriteTokens count prompt tokens served from
+	// and written to the provider's prompt cache. They are not included in
+	// TokensIn.
+	CacheReadTokens  int
+	CacheWriteTokens int
+
+	// ToolCalls are the calls the model made. The caller runs them and sends
+	// the results in a follow-up request.
+	ToolCalls []ToolCall
+
+	// Thinking holds the thinking and redacted thinking blocks that preceded
+	// the answer. They must be sent back, unchanged, ahead of the tool calls
+	// when replying with tool results.
+	Thinking []ContentBlock
+
+	// TokensEstimated is set when the provider did not report usage and
+	// TokensIn and TokensOut were estimated locally.
+	TokensEstimated bool
 }
 
 // Client is the interface for LLM providers.
@@ -41,6 +119,9 @@ type Client interface {
 	// CompleteStream sends a streaming completion request.
 	CompleteStream(ctx context.Context, req *CompletionRequest, callback StreamCallback) (*CompletionResponse, error)
 
+	// Validate checks a request's model and settings against the provider's limits.
+	Validate(req *CompletionRequest) error
+
 	// Name returns the provider name.
 	Name() string
 
@@ -0,0 +1,70 @@
+package llm
+
+// Content block types.
+const (
+	BlockText       = "text"
+	BlockImage      = "image"
+	BlockToolUse    = "tool_use"
+	BlockToolResult = "tool_result"
+
+	BlockThinking         = "thinking"
+	BlockRedactedThinking = "redacted_thinking"
+)
+
+// ContentBlock is one typed part of a chat message's content.
+type ContentBlock struct {
+	Type string `json:"type"`
+
+	// Text blocks
+	Text string `json:"text,omitempty"`
+
+	// Image blocks
+	Image *Image `json:"image,omitempty"`
+
+	// Tool use blocks
+	ToolCall *ToolCall `json:"tool_call,omitempty"`
+
+	// Tool result blocks. Content holds the result's text and image blocks.
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	Content    []ContentBlock `json:"content,omitempty"`
+	IsError    bool           `json:"is_error,omitempty"`
+
+	// Thinking blocks, signed by the provider. Redacted thinking blocks carry
+	// the reasoning encrypted in Data instead.
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+// Image is an inline, base64 encoded image.
+type Image struct {
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// ContentBlocks returns the message's content as typed blocks, built from
+// Content and the tool call fields when Blocks is not set.
+func (m ChatMessage) ContentBlocks() []ContentBlock {
+	if len(m.Blocks) > 0 {
+		return m.Blocks
+	}
+
+	if m.Role == "tool" {
+		return []ContentBlock{{
+			Type:       BlockToolResult,
+			ToolCallID: m.ToolCallID,
+			Content:    []ContentBlock{{Type: BlockText, Text: m.Content}},
+			IsError:    m.IsError,
+		}}
+	}
+
+	var blocks []ContentBlock
+	// A tool call may come without text
+	if m.Content != "" || len(m.ToolCalls) == 0 {
+		blocks = append(blocks, ContentBlock{Type: BlockText, Text: m.Content})
+	}
+	for i := range m.ToolCalls {
+		blocks = append(blocks, ContentBlock{Type: BlockToolUse, ToolCall: &m.ToolCalls[i]})
+	}
+	return blocks
+}
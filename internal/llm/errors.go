@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrRateLimited is returned when the provider throttles or is overloaded.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrTimeout is returned when a request exceeds its deadline.
+	ErrTimeout = errors.New("request timed out")
+
+	// ErrAuth is returned when the provider rejects the credentials.
+	ErrAuth = errors.New("authentication failed")
+
+	// ErrInvalidRequest is returned when the provider rejects the request itself.
+	ErrInvalidRequest = errors.New("invalid request")
+
+	// ErrProvider is returned for any other provider failure.
+	ErrProvider = errors.New("provider error")
+)
+
+// ProviderError is a classified failure from an LLM provider. It matches one of
+// the sentinel errors above with errors.Is.
+type ProviderError struct {
+	Provider   string
+	Kind       error
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+// Error implements the error interface.
+func (e *ProviderError) Error() string {
+	if e.StatusCode > 0 {
+		return fmt.Sprintf("%s: %v (status %d): %v", e.Provider, e.Kind, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s: %v: %v", e.Provider, e.Kind, e.Err)
+}
+
+// Unwrap exposes both the classification and the underlying error.
+func (e *ProviderError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// classifyError wraps a provider failure in a ProviderError. status and header
+// describe the HTTP response, when there was one.
+func classifyError(ctx context.Context, provider string, err error, status int, header http.Header) error {
+	if err == nil {
+		return nil
+	}
+
+	var existing *ProviderError
+	if errors.As(err, &existing) {
+		return err
+	}
+
+	perr := &ProviderError{
+		Provider:   provider,
+		StatusCode: status,
+		Err:        err,
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		perr.Kind = ErrTimeout
+	case status == http.StatusTooManyRequests || status == 529 || status == http.StatusServiceUnavailable:
+		// 529 is Anthropic's "overloaded"
+		perr.Kind = ErrRateLimited
+		perr.RetryAfter = parseRetryAfter(header)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		perr.Kind = ErrAuth
+	case status == http.StatusBadRequest || status == http.StatusNotFound ||
+		status == http.StatusRequestEntityTooLarge || status == http.StatusUnprocessableEntity:
+		perr.Kind = ErrInvalidRequest
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		perr.Kind = ErrTimeout
+	default:
+		perr.Kind = ErrProvider
+	}
+
+	return perr
+}
+
+// parseRetryAfter reads the provider's retry hint, in seconds or as an HTTP date.
+func parseRetryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+
+	// Anthropic and OpenAI both send the standard header; some gateways only
+	// send the millisecond variant.
+	if ms := header.Get("Retry-After-Ms"); ms != "" {
+		if v, err := strconv.ParseFloat(ms, 64); err == nil && v > 0 {
+			return time.Duration(v * float64(time.Millisecond))
+		}
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,283 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy bounds how a FailoverClient retries a model before moving on.
+type RetryPolicy struct {
+	// MaxAttempts is the number of tries per model, including the first.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry; it doubles per retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between retries, including provider hints.
+	MaxBackoff time.Duration
+}
+
+// Failover describes a failed attempt and what is tried next.
+type Failover struct {
+	Attempt      int
+	Provider     string
+	Model        string
+	Err          error
+	NextProvider string
+	NextModel    string
+	Backoff      time.Duration
+}
+
+// FallbackChains maps tenant IDs to their ordered model fallback chain. The
+// "*" entry applies to tenants without their own.
+type FallbackChains map[string][]string
+
+// For returns the fallback chain of a tenant.
+func (f FallbackChains) For(tenantID string) []string {
+	if chain, ok := f[tenantID]; ok {
+		return chain
+	}
+	return f["*"]
+}
+
+// FailoverClient retries failed requests with backoff and then falls back
+// along the request's fallback chain. It gives up as soon as a token has been
+// streamed, since a retry would repeat output the caller already relayed, and
+// on requests the provider rejects as invalid, which no retry or fallback fixes.
+//
+// Each provider and model sits behind a circuit breaker: models whose breaker
+// is open are skipped without waiting on them.
+type FailoverClient struct {
+	registry *Registry
+	breakers *Breakers
+	policy   RetryPolicy
+}
+
+// NewFailoverClient wraps a registry with retries, failover and circuit breakers.
+func NewFailoverClient(registry *Registry, breakers *Breakers, policy RetryPolicy) *FailoverClient {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &FailoverClient{
+		registry: registry,
+		breakers: breakers,
+		policy:   policy,
+	}
+}
+
+// attempt tracks the progress of one call to a provider.
+type attempt struct {
+	start      time.Time
+	firstToken time.Time
+}
+
+// latency is the time to the first token for streams and the total time otherwise.
+func (a *attempt) latency() time.Duration {
+	if !a.firstToken.IsZero() {
+		return a.firstToken.Sub(a.start)
+	}
+	return time.Since(a.start)
+}
+
+// Complete sends a completion request, retrying and failing over as needed.
+func (c *FailoverClient) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	return c.do(ctx, req, func(client Client, routed *CompletionRequest, _ *attempt) (*CompletionResponse, error) {
+		return client.Complete(ctx, routed)
+	})
+}
+
+// CompleteStream sends a streaming completion request, retrying and failing
+// over until the first token is emitted.
+func (c *FailoverClient) CompleteStream(ctx context.Context, req *CompletionRequest, callback StreamCallback) (*CompletionResponse, error) {
+	return c.do(ctx, req, func(client Client, routed *CompletionRequest, a *attempt) (*CompletionResponse, error) {
+		return client.CompleteStream(ctx, routed, func(token string, index int) error {
+			if a.firstToken.IsZero() {
+				a.firstToken = time.Now()
+			}
+			return callback(token, index)
+		})
+	})
+}
+
+// Check returns a BreakerOpenError if the breaker of the request's model and
+// of every fallback is open, so callers can turn the request away up front.
+func (c *FailoverClient) Check(req *CompletionRequest) error {
+	var firstErr error
+	for _, m := range append([]string{req.Model}, req.Fallbacks...) {
+		client, model, err := c.registry.Resolve(m)
+		if err != nil {
+			continue
+		}
+		err = c.breakers.Check(client.Name(), model)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Resolve returns the provider serving a model along with the model name.
+func (c *FailoverClient) Resolve(model string) (Client, string, error) {
+	return c.registry.Resolve(model)
+}
+
+// Validate checks a request against the limits of the provider serving its model.
+func (c *FailoverClient) Validate(req *CompletionRequest) error {
+	return c.registry.Validate(req)
+}
+
+// Name returns the provider name.
+func (c *FailoverClient) Name() string {
+	return c.registry.Name()
+}
+
+// Models returns every model served, the default provider's first.
+func (c *FailoverClient) Models() []string {
+	return c.registry.Models()
+}
+
+// do runs call against the requested model and then each fallback until one
+// succeeds. Once output has reached the caller, or if the request itself was
+// rejected, failures are returned as they are.
+func (c *FailoverClient) do(
+	ctx context.Context,
+	req *CompletionRequest,
+	call func(Client, *CompletionRequest, *attempt) (*CompletionResponse, error),
+) (*CompletionResponse, error) {
+	models := append([]string{req.Model}, req.Fallbacks...)
+
+	var lastErr error
+	attempts := 0
+	tried := make(map[string]bool)
+	for i := 0; i < len(models); i++ {
+		client, model, err := c.registry.Resolve(models[i])
+		if err != nil {
+			// A misconfigured fallback should not mask the original failure
+			if lastErr == nil {
+				lastErr = err
+			}
+			continue
+		}
+		if tried[model] {
+			continue
+		}
+		tried[model] = true
+
+		routed := *req
+		routed.Model = model
+
+		for try := 1; ; try++ {
+			attempts++
+			failover := Failover{
+				Attempt:  attempts,
+				Provider: client.Name(),
+				Model:    model,
+			}
+
+			done, err := c.breakers.Allow(client.Name(), model)
+			if err != nil {
+				// Fail fast; retrying an open breaker cannot succeed
+				lastErr = err
+				failover.Err = err
+				c.failover(req, failover, models[i+1:], tried)
+				break
+			}
+
+			a := &attempt{start: time.Now()}
+			resp, err := call(client, &routed, a)
+			outcome := err
+			if err != nil && ctx.Err() != nil {
+				// Our own cancel or deadline, not the provider, ended the call
+				outcome = ctx.Err()
+			}
+			done(outcome, a.latency())
+			if err == nil {
+				return resp, nil
+			}
+			lastErr = err
+			failover.Err = err
+
+			if !a.firstToken.IsZero() || ctx.Err() != nil {
+				return nil, err
+			}
+
+			// A request one provider rejects is not worth sending to the next
+			if errors.Is(err, ErrInvalidRequest) {
+				return nil, err
+			}
+
+			if try < c.policy.MaxAttempts && isRetryable(err) {
+				failover.NextProvider, failover.NextModel = client.Name(), model
+				failover.Backoff = c.backoff(try, err)
+				notify(req, failover)
+
+				select {
+				case <-ctx.Done():
+					return nil, err
+				case <-time.After(failover.Backoff):
+				}
+				continue
+			}
+
+			c.failover(req, failover, models[i+1:], tried)
+			break
+		}
+	}
+
+	return nil, lastErr
+}
+
+// failover reports a failed attempt that moves on to the next fallback, if any.
+func (c *FailoverClient) failover(req *CompletionRequest, failover Failover, remaining []string, tried map[string]bool) {
+	if next, nextModel, ok := c.next(remaining, tried); ok {
+		failover.NextProvider, failover.NextModel = next.Name(), nextModel
+		notify(req, failover)
+	}
+}
+
+// next returns the first resolvable model among the remaining fallbacks.
+func (c *FailoverClient) next(models []string, tried map[string]bool) (Client, string, bool) {
+	for _, m := range models {
+		client, model, err := c.registry.Resolve(m)
+		if err == nil && !tried[model] {
+			return client, model, true
+		}
+	}
+	return nil, "", false
+}
+
+// backoff returns the delay before retry number try, honouring the
+// provider's Retry-After hint.
+func (c *FailoverClient) backoff(try int, err error) time.Duration {
+	delay := c.policy.InitialBackoff << (try - 1)
+	// Jitter keeps replicas from retrying in lockstep
+	if delay > 0 {
+		delay = delay/2 + rand.N(delay/2+1)
+	}
+
+	var perr *ProviderError
+	if errors.As(err, &perr) && perr.RetryAfter > delay {
+		delay = perr.RetryAfter
+	}
+
+	if c.policy.MaxBackoff > 0 && delay > c.policy.MaxBackoff {
+		delay = c.policy.MaxBackoff
+	}
+	return delay
+}
+
+// isRetryable reports whether trying the same model again may succeed.
+func isRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrTimeout) || errors.Is(err, ErrProvider)
+}
+
+func notify(req *CompletionRequest, failover Failover) {
+	if req.OnFailover != nil {
+		req.OnFailover(failover)
+	}
+}
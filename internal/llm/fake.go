@@ -0,0 +1,303 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Failures the fake provider can inject.
+const (
+	FakeFailRateLimit = "rate_limit"
+	FakeFailTimeout   = "timeout"
+	FakeFailProvider  = "provider"
+	FakeFailMidStream = "mid_stream"
+)
+
+// FakeConfig configures the fake provider.
+type FakeConfig struct {
+	// Responses are returned in turn, wrapping around. When empty, the last
+	// user message is echoed back.
+	Responses []string
+
+	// TokenDelay is the pause before each streamed token.
+	TokenDelay time.Duration
+
+	// Fail injects a failure into every request. A single request can also
+	// ask for one by including "[fail:<kind>]" in its last user message.
+	Fail string
+}
+
+// FakeClient is a deterministic provider for development and tests. It needs
+// no network access and streams scripted or echoed responses word by word.
+// A user message containing "[tool:<name>]" makes it call that tool, if the
+// request declares it, and its reply to the result echoes the result.
+// Requests with a thinking budget get a short reasoning block first.
+type FakeClient struct {
+	config FakeConfig
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewFakeClient creates a fake provider.
+func NewFakeClient(config FakeConfig) *FakeClient {
+	return &FakeClient{config: config}
+}
+
+// Name returns the provider name.
+func (c *FakeClient) Name() string {
+	return "fake"
+}
+
+// Models returns available models.
+func (c *FakeClient) Models() []string {
+	return []string{"fake-model", "fake-model-backup"}
+}
+
+// Complete sends a completion request.
+func (c *FakeClient) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	return c.CompleteStream(ctx, req, func(string, int) error { return nil })
+}
+
+// CompleteStream sends a streaming completion request.
+func (c *FakeClient) CompleteStream(ctx context.Context, req *CompletionRequest, callback StreamCallback) (*CompletionResponse, error) {
+	start := time.Now()
+
+	model := req.Model
+	if model == "" {
+		model = c.Models()[0]
+	}
+
+	fail := c.failure(req)
+	switch fail {
+	case FakeFailRateLimit, FakeFailTimeout, FakeFailProvider:
+		return nil, c.injectedError(fail)
+	}
+
+	thinking, err := c.think(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if call, ok := c.toolCall(req); ok {
+		return &CompletionResponse{
+			Provider:        c.Name(),
+			Model:           model,
+			TokensIn:        EstimateRequestTokens(req),
+			StopReason:      "tool_use",
+			LatencyMs:       time.Since(start).Milliseconds(),
+			TokensEstimated: true,
+			ToolCalls:       []ToolCall{call},
+			Thinking:        thinking,
+		}, nil
+	}
+
+	tokens, stopReason := c.tokens(req)
+	failAt := -1
+	if fail == FakeFailMidStream {
+		failAt = max(len(tokens)/2, 1)
+	}
+
+	var content string
+	for i, token := range tokens {
+		if i == failAt {
+			return nil, c.injectedError(fail)
+		}
+
+		if c.config.TokenDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(c.config.TokenDelay):
+			}
+		}
+
+		content += token
+		if err := callback(token, i); err != nil {
+			return nil, err
+		}
+	}
+	if failAt >= len(tokens) {
+		return nil, c.injectedError(fail)
+	}
+
+	return &CompletionResponse{
+		Content:         content,
+		Provider:        c.Name(),
+		Model:           model,
+		TokensIn:        EstimateRequestTokens(req),
+		TokensOut:       len(tokens),
+		StopReason:      stopReason,
+		LatencyMs:       time.Since(start).Milliseconds(),
+		TokensEstimated: true,
+		Thinking:        thinking,
+	}, nil
+}
+
+// Validate checks a request against the fake provider's limits.
+func (c *FakeClient) Validate(req *CompletionRequest) error {
+	if err := checkModel(c, req.Model); err != nil {
+		return err
+	}
+	if err := checkRange("temperature", req.Temperature, 0, 2); err != nil {
+		return err
+	}
+	if err := checkRange("top_p", req.TopP, 0, 1); err != nil {
+		return err
+	}
+	if err := checkThinkingBudget(req.ThinkingBudget, req.MaxTokens); err != nil {
+		return err
+	}
+	return checkStopSequences(req.StopSequences, 0)
+}
+
+// think streams a scripted reasoning block to OnThinking when the request
+// has a thinking budget.
+func (c *FakeClient) think(ctx context.Context, req *CompletionRequest) ([]ContentBlock, error) {
+	if req.ThinkingBudget == 0 {
+		return nil, nil
+	}
+
+	thinking := "The user said: " + lastUserMessage(req)
+	if req.OnThinking != nil {
+		for _, delta := range strings.SplitAfter(thinking, " ") {
+			if c.config.TokenDelay > 0 {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(c.config.TokenDelay):
+				}
+			}
+			if err := req.OnThinking(delta); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return []ContentBlock{{
+		Type:      BlockThinking,
+		Thinking:  thinking,
+		Signature: "fake-signature",
+	}}, nil
+}
+
+// failure returns the failure to inject into a request, if any.
+func (c *FakeClient) failure(req *CompletionRequest) string {
+	last := lastUserMessage(req)
+	if i := strings.Index(last, "[fail:"); i >= 0 {
+		if j := strings.Index(last[i:], "]"); j >= 0 {
+			return last[i+len("[fail:") : i+j]
+		}
+	}
+	return c.config.Fail
+}
+
+// toolCall returns the tool call a request asks for, if any. Only a user
+// message can ask, so the reply to the tool result is text.
+func (c *FakeClient) toolCall(req *CompletionRequest) (ToolCall, bool) {
+	n := len(req.Messages)
+	if n == 0 || req.Messages[n-1].Role != "user" {
+		return ToolCall{}, false
+	}
+
+	last := req.Messages[n-1].Content
+	i := strings.Index(last, "[tool:")
+	if i < 0 {
+		return ToolCall{}, false
+	}
+	j := strings.Index(last[i:], "]")
+	if j < 0 {
+		return ToolCall{}, false
+	}
+	name := last[i+len("[tool:") : i+j]
+
+	for _, tool := range req.Tools {
+		if tool.Name == name {
+			return ToolCall{
+				ID:        fmt.Sprintf("fake_call_%d", n),
+				Name:      name,
+				Arguments: json.RawMessage("{}"),
+			}, true
+		}
+	}
+	return ToolCall{}, false
+}
+
+// injectedError returns the error a failure is reported as.
+func (c *FakeClient) injectedError(fail string) error {
+	perr := &ProviderError{
+		Provider: c.Name(),
+		Err:      fmt.Errorf("injected %s failure", fail),
+	}
+
+	switch fail {
+	case FakeFailRateLimit:
+		perr.Kind = ErrRateLimited
+		perr.StatusCode = 429
+		perr.RetryAfter = time.Second
+	case FakeFailTimeout:
+		perr.Kind = ErrTimeout
+	default:
+		perr.Kind = ErrProvider
+	}
+
+	return perr
+}
+
+// tokens returns the tokens to stream for a request, split after each word,
+// and the stop reason. The response is cut at the first stop sequence and
+// at MaxTokens.
+func (c *FakeClient) tokens(req *CompletionRequest) ([]string, string) {
+	response := c.response(req)
+	stopReason := "end_turn"
+
+	for _, seq := range req.StopSequences {
+		if i := strings.Index(response, seq); i >= 0 {
+			response = response[:i]
+			stopReason = "stop_sequence"
+		}
+	}
+
+	tokens := strings.SplitAfter(response, " ")
+	if tokens[len(tokens)-1] == "" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if req.MaxTokens > 0 && len(tokens) > req.MaxTokens {
+		tokens = tokens[:req.MaxTokens]
+		stopReason = "max_tokens"
+	}
+
+	return tokens, stopReason
+}
+
+// response returns the echoed tool result, the next scripted response, or
+// the echoed user message.
+func (c *FakeClient) response(req *CompletionRequest) string {
+	if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == "tool" {
+		return req.Messages[n-1].Content
+	}
+	if len(c.config.Responses) == 0 {
+		return lastUserMessage(req)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	response := c.config.Responses[c.next%len(c.config.Responses)]
+	c.next++
+	return response
+}
+
+// lastUserMessage returns the content of the last user message in a request.
+func lastUserMessage(req *CompletionRequest) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			return req.Messages[i].Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// localDiscoveryTimeout bounds the model list request made at startup.
+const localDiscoveryTimeout = 10 * time.Second
+
+// LocalClient serves self-hosted models through an OpenAI-compatible server
+// such as Ollama, vLLM or the llama.cpp server. Its models are whatever the
+// server lists at /v1/models when the client is created.
+type LocalClient struct {
+	*OpenAIClient
+}
+
+// NewLocalClient creates a client for the server at baseURL, which includes
+// the /v1 prefix (e.g. http://localhost:11434/v1). apiKey may be empty for
+// servers without authentication.
+func NewLocalClient(ctx context.Context, name, baseURL, apiKey string) (*LocalClient, error) {
+	if baseURL == "" {
+		return nil, errors.New("local LLM base URL is required")
+	}
+
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = strings.TrimSuffix(baseURL, "/")
+	c := newOpenAIClient(config, name, nil)
+
+	ctx, cancel := context.WithTimeout(ctx, localDiscoveryTimeout)
+	defer cancel()
+
+	list, err := c.client.ListModels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models from %s: %w", baseURL, err)
+	}
+	for _, m := range list.Models {
+		c.models = append(c.models, m.ID)
+	}
+	if len(c.models) == 0 {
+		return nil, fmt.Errorf("no models available from %s", baseURL)
+	}
+
+	return &LocalClient{OpenAIClient: c}, nil
+}
+
+// Validate checks a request against the limits OpenAI-compatible servers
+// share. Output length is bounded by the model's context window, which is
+// all that is known about an arbitrary local model.
+func (c *LocalClient) Validate(req *CompletionRequest) error {
+	if err := checkModel(c, req.Model); err != nil {
+		return err
+	}
+	if err := checkRange("temperature", req.Temperature, 0, 2); err != nil {
+		return err
+	}
+	if err := checkRange("top_p", req.TopP, 0, 1); err != nil {
+		return err
+	}
+	if err := checkMaxTokens(req.MaxTokens, ContextWindow(req.Model)); err != nil {
+		return err
+	}
+	if err := checkNoThinking(c, req.ThinkingBudget); err != nil {
+		return err
+	}
+	return checkStopSequences(req.StopSequences, 0)
+}
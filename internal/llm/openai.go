@@ -2,8 +2,12 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
+	"math"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/sashabaranov/go-openai"
@@ -12,6 +16,8 @@ import (
 // OpenAIClient is the OpenAI LLM client.
 type OpenAIClient struct {
 	client *openai.Client
+	name   string
+	models []string
 }
 
 // NewOpenAIClient creates a new OpenAI client.
@@ -20,65 +26,51 @@ func NewOpenAIClient(apiKey string) (*OpenAIClient, error) {
 		return nil, errors.New("OpenAI API key is required")
 	}
 
-	client := openai.NewClient(apiKey)
+	return newOpenAIClient(openai.DefaultConfig(apiKey), "openai", []string{
+		"gpt-4o",
+		"gpt-4o-mini",
+		"gpt-4-turbo",
+		"gpt-4",
+		"gpt-3.5-turbo",
+	}), nil
+}
+
+// newOpenAIClient creates a client for any server speaking OpenAI's API.
+func newOpenAIClient(config openai.ClientConfig, name string, models []string) *OpenAIClient {
+	config.HTTPClient = &http.Client{Transport: &headerCapture{base: http.DefaultTransport}}
 
 	return &OpenAIClient{
-		client: client,
-	}, nil
+		client: openai.NewClientWithConfig(config),
+		name:   name,
+		models: models,
+	}
 }
 
 // Name returns the provider name.
 func (c *OpenAIClient) Name() string {
-	return "openai"
+	return c.name
 }
 
 // Models returns available models.
 func (c *OpenAIClient) Models() []string {
-	return []string{
-		"gpt-4o",
-		"gpt-4o-mini",
-		"gpt-4-turbo",
-		"gpt-4",
-		"gpt-3.5-turbo",
-	}
+	return c.models
 }
 
 // Complete sends a completion request.
 func (c *OpenAIClient) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
 	start := time.Now()
 
-	model := req.Model
-	if model == "" {
-		model = "gpt-4o"
-	}
-
-	maxTokens := req.MaxTokens
-	if maxTokens == 0 {
-		maxTokens = 4096
-	}
-
-	// Convert messages to OpenAI format
-	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
-	for i, msg := range req.Messages {
-		messages[i] = openai.ChatCompletionMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		}
-	}
-
-	resp, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:       model,
-		Messages:    messages,
-		MaxTokens:   maxTokens,
-		Temperature: float32(req.Temperature),
-	})
+	ctx, header := withResponseHeader(ctx)
+	resp, err := c.client.CreateChatCompletion(ctx, c.newRequest(req))
 	if err != nil {
-		return nil, err
+		return nil, c.wrapError(ctx, err, header)
 	}
 
 	var content string
+	var toolCalls []ToolCall
 	if len(resp.Choices) > 0 {
 		content = resp.Choices[0].Message.Content
+		toolCalls = openAIToolCalls(resp.Choices[0].Message.ToolCalls)
 	}
 
 	stopReason := ""
@@ -88,21 +80,50 @@ func (c *OpenAIClient) Complete(ctx context.Context, req *CompletionRequest) (*C
 
 	return &CompletionResponse{
 		Content:    content,
+		Provider:   c.Name(),
 		Model:      resp.Model,
 		TokensIn:   resp.Usage.PromptTokens,
 		TokensOut:  resp.Usage.CompletionTokens,
 		StopReason: stopReason,
 		LatencyMs:  time.Since(start).Milliseconds(),
+		ToolCalls:  toolCalls,
 	}, nil
 }
 
-// CompleteStream sends a streaming completion request.
-func (c *OpenAIClient) CompleteStream(ctx context.Context, req *CompletionRequest, callback StreamCallback) (*CompletionResponse, error) {
-	start := time.Now()
+// Validate checks a request against OpenAI's limits.
+func (c *OpenAIClient) Validate(req *CompletionRequest) error {
+	if err := checkModel(c, req.Model); err != nil {
+		return err
+	}
+	if err := checkRange("temperature", req.Temperature, 0, 2); err != nil {
+		return err
+	}
+	if err := checkRange("top_p", req.TopP, 0, 1); err != nil {
+		return err
+	}
+	if err := checkMaxTokens(req.MaxTokens, openAIMaxOutput(req.Model)); err != nil {
+		return err
+	}
+	if err := checkNoThinking(c, req.ThinkingBudget); err != nil {
+		return err
+	}
+	return checkStopSequences(req.StopSequences, 4)
+}
+
+// openAIMaxOutput returns the largest completion a GPT model can produce.
+func openAIMaxOutput(model string) int {
+	if strings.HasPrefix(model, "gpt-4o") || model == "" {
+		return 16384
+	}
+	return 4096
+}
 
+// newRequest converts a request to OpenAI's format. The system prompt is sent
+// as a leading system message.
+func (c *OpenAIClient) newRequest(req *CompletionRequest) openai.ChatCompletionRequest {
 	model := req.Model
-	if model == "" {
-		model = "gpt-4o"
+	if model == "" && len(c.models) > 0 {
+		model = c.models[0]
 	}
 
 	maxTokens := req.MaxTokens
@@ -111,28 +132,156 @@ func (c *OpenAIClient) CompleteStream(ctx context.Context, req *CompletionReques
 	}
 
 	// Convert messages to OpenAI format
-	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
-	for i, msg := range req.Messages {
-		messages[i] = openai.ChatCompletionMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
+	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: req.System,
+		})
+	}
+	for _, msg := range req.Messages {
+		messages = append(messages, openAIMessages(msg)...)
+	}
+
+	chatReq := openai.ChatCompletionRequest{
+		Model:     model,
+		Messages:  messages,
+		MaxTokens: maxTokens,
+		Stop:      req.StopSequences,
+	}
+	// go-openai omits zero values, so an explicit 0 falls back to the
+	// provider default of 1; the smallest float32 is greedy in practice
+	if req.Temperature != nil {
+		chatReq.Temperature = max(float32(*req.Temperature), math.SmallestNonzeroFloat32)
+	}
+	if req.TopP != nil {
+		chatReq.TopP = max(float32(*req.TopP), math.SmallestNonzeroFloat32)
+	}
+	if req.ResponseFormat != nil {
+		// Not strict: strict mode rejects most schemas that do not forbid
+		// additional properties, and replies are validated anyway
+		chatReq.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   req.ResponseFormat.Name,
+				Schema: req.ResponseFormat.Schema,
+			},
 		}
 	}
+	for _, tool := range req.Tools {
+		chatReq.Tools = append(chatReq.Tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
 
-	stream, err := c.client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
-		Model:       model,
-		Messages:    messages,
-		MaxTokens:   maxTokens,
-		Temperature: float32(req.Temperature),
-		Stream:      true,
-	})
+	return chatReq
+}
+
+// openAIMessages converts a message to OpenAI's format. Each tool result
+// becomes a "tool" message of its own, and text with images is sent as a
+// multi-part message.
+func openAIMessages(msg ChatMessage) []openai.ChatCompletionMessage {
+	var messages []openai.ChatCompletionMessage
+	var parts []openai.ChatMessagePart
+	var calls []openai.ToolCall
+	for _, block := range msg.ContentBlocks() {
+		switch block.Type {
+		case BlockText:
+			if block.Text != "" {
+				parts = append(parts, openai.ChatMessagePart{
+					Type: openai.ChatMessagePartTypeText,
+					Text: block.Text,
+				})
+			}
+		case BlockImage:
+			parts = append(parts, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{
+					URL: "data:" + block.Image.MediaType + ";base64," + block.Image.Data,
+				},
+			})
+		case BlockToolUse:
+			calls = append(calls, openai.ToolCall{
+				ID:   block.ToolCall.ID,
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      block.ToolCall.Name,
+					Arguments: string(block.ToolCall.Arguments),
+				},
+			})
+		case BlockToolResult:
+			// Tool messages only take text
+			var texts []string
+			for _, part := range block.Content {
+				if part.Type == BlockText {
+					texts = append(texts, part.Text)
+				}
+			}
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    strings.Join(texts, ""),
+				ToolCallID: block.ToolCallID,
+			})
+		}
+	}
+
+	if len(messages) > 0 && len(parts) == 0 && len(calls) == 0 {
+		return messages
+	}
+
+	chatMsg := openai.ChatCompletionMessage{
+		Role:      msg.Role,
+		ToolCalls: calls,
+	}
+	if len(parts) == 1 && parts[0].Type == openai.ChatMessagePartTypeText {
+		chatMsg.Content = parts[0].Text
+	} else if len(parts) > 0 {
+		chatMsg.MultiContent = parts
+	}
+	return append(messages, chatMsg)
+}
+
+// openAIToolCalls converts the tool calls of an OpenAI message.
+func openAIToolCalls(calls []openai.ToolCall) []ToolCall {
+	var toolCalls []ToolCall
+	for _, call := range calls {
+		args := json.RawMessage(call.Function.Arguments)
+		if len(args) == 0 {
+			args = json.RawMessage("{}")
+		}
+		toolCalls = append(toolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: args,
+		})
+	}
+	return toolCalls
+}
+
+// CompleteStream sends a streaming completion request.
+func (c *OpenAIClient) CompleteStream(ctx context.Context, req *CompletionRequest, callback StreamCallback) (*CompletionResponse, error) {
+	start := time.Now()
+
+	chatReq := c.newRequest(req)
+	chatReq.Stream = true
+	chatReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	ctx, header := withResponseHeader(ctx)
+	stream, err := c.client.CreateChatCompletionStream(ctx, chatReq)
 	if err != nil {
-		return nil, err
+		return nil, c.wrapError(ctx, err, header)
 	}
 	defer stream.Close()
 
-	var content string
+	var content, served string
 	var stopReason string
+	var usage *openai.Usage
+	var calls []openai.ToolCall
 	index := 0
 
 	for {
@@ -141,7 +290,12 @@ func (c *OpenAIClient) CompleteStream(ctx context.Context, req *CompletionReques
 			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, c.wrapError(ctx, err, header)
+		}
+
+		// Chunks name the model that served the request, e.g. a dated snapshot
+		if served == "" {
+			served = response.Model
 		}
 
 		if len(response.Choices) > 0 {
@@ -154,23 +308,106 @@ func (c *OpenAIClient) CompleteStream(ctx context.Context, req *CompletionReques
 				index++
 			}
 
+			// Tool calls stream as fragments addressed by index
+			for _, delta := range response.Choices[0].Delta.ToolCalls {
+				i := len(calls)
+				switch {
+				case delta.Index != nil:
+					i = *delta.Index
+				case delta.ID == "" && len(calls) > 0:
+					i = len(calls) - 1
+				}
+				for len(calls) <= i {
+					calls = append(calls, openai.ToolCall{})
+				}
+				if delta.ID != "" {
+					calls[i].ID = delta.ID
+				}
+				if delta.Function.Name != "" {
+					calls[i].Function.Name = delta.Function.Name
+				}
+				calls[i].Function.Arguments += delta.Function.Arguments
+			}
+
 			if response.Choices[0].FinishReason != "" {
 				stopReason = string(response.Choices[0].FinishReason)
 			}
 		}
+
+		// The usage chunk comes last, with no choices
+		if response.Usage != nil {
+			usage = response.Usage
+		}
 	}
 
-	// Note: OpenAI streaming doesn't provide token counts
-	// We estimate based on content length
-	tokensIn := len(content) / 4  // Rough estimate
-	tokensOut := len(content) / 4 // Rough estimate
+	if served == "" {
+		served = chatReq.Model
+	}
 
-	return &CompletionResponse{
+	resp := &CompletionResponse{
 		Content:    content,
-		Model:      model,
-		TokensIn:   tokensIn,
-		TokensOut:  tokensOut,
+		Provider:   c.Name(),
+		Model:      served,
 		StopReason: stopReason,
 		LatencyMs:  time.Since(start).Milliseconds(),
-	}, nil
+		ToolCalls:  openAIToolCalls(calls),
+	}
+	if usage != nil {
+		resp.TokensIn = usage.PromptTokens
+		resp.TokensOut = usage.CompletionTokens
+	} else {
+		// Not every OpenAI-compatible server honours include_usage
+		resp.TokensIn = EstimateRequestTokens(req)
+		resp.TokensOut = EstimateTokens(req.Model, ChatMessage{Content: content, ToolCalls: resp.ToolCalls}) - messageOverhead
+		resp.TokensEstimated = true
+	}
+
+	return resp, nil
+}
+
+// wrapError classifies an OpenAI API failure.
+func (c *OpenAIClient) wrapError(ctx context.Context, err error, header *http.Header) error {
+	status := 0
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	}
+
+	return classifyError(ctx, c.Name(), err, status, *header)
+}
+
+// responseHeaderKey is the context key under which headerCapture records
+// response headers.
+type responseHeaderKey struct{}
+
+// withResponseHeader returns a context that makes headerCapture record the
+// response headers of the request it is used for. go-openai drops the
+// headers of failed responses, and Retry-After lives there.
+func withResponseHeader(ctx context.Context) (context.Context, *http.Header) {
+	header := new(http.Header)
+	return context.WithValue(ctx, responseHeaderKey{}, header), header
+}
+
+// headerCapture is a transport that stores response headers for requests
+// made with withResponseHeader.
+type headerCapture struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *headerCapture) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if header, ok := req.Context().Value(responseHeaderKey{}).(*http.Header); ok {
+		*header = resp.Header
+	}
+
+	return resp, nil
 }
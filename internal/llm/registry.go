@@ -0,0 +1,154 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrUnknownModel is returned when no registered provider serves a model.
+var ErrUnknownModel = errors.New("unknown model")
+
+// Resolver is implemented by clients that route each model to the provider
+// serving it.
+type Resolver interface {
+	// Resolve returns the provider serving a model along with the model name,
+	// or an error matching ErrUnknownModel if no provider serves it.
+	Resolve(model string) (Client, string, error)
+}
+
+// Registry holds every configured provider and routes each request to the
+// provider that lists its model. Requests without a model go to the default
+// provider's default model, the first one it lists. A Registry is itself a
+// Client, so callers need not know how many providers are configured.
+type Registry struct {
+	defaultProvider string
+	clients         []Client
+}
+
+// NewRegistry creates an empty registry. defaultProvider names the provider
+// serving requests that do not pick a model; if it is not registered, the
+// first registered provider is used.
+func NewRegistry(defaultProvider string) *Registry {
+	return &Registry{defaultProvider: defaultProvider}
+}
+
+// Register adds a provider. Models listed by several providers are served by
+// the one registered first.
+func (r *Registry) Register(c Client) {
+	r.clients = append(r.clients, c)
+}
+
+// Len returns the number of registered providers.
+func (r *Registry) Len() int {
+	return len(r.clients)
+}
+
+// Default returns the provider serving requests without a model, or nil if
+// none is registered.
+func (r *Registry) Default() Client {
+	for _, c := range r.clients {
+		if c.Name() == r.defaultProvider {
+			return c
+		}
+	}
+	if len(r.clients) > 0 {
+		return r.clients[0]
+	}
+	return nil
+}
+
+// Resolve returns the provider serving a model along with the model name,
+// which is the default model when model is empty.
+func (r *Registry) Resolve(model string) (Client, string, error) {
+	if model == "" {
+		c := r.Default()
+		if c == nil || len(c.Models()) == 0 {
+			return nil, "", fmt.Errorf("%w: no provider configured", ErrUnknownModel)
+		}
+		return c, c.Models()[0], nil
+	}
+
+	for _, c := range r.clients {
+		if slices.Contains(c.Models(), model) {
+			return c, model, nil
+		}
+	}
+
+	return nil, "", fmt.Errorf("%w: %q", ErrUnknownModel, model)
+}
+
+// Complete routes a completion request to the provider serving its model.
+func (r *Registry) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	c, routed, err := r.route(req)
+	if err != nil {
+		return nil, err
+	}
+	return c.Complete(ctx, routed)
+}
+
+// CompleteStream routes a streaming completion request to the provider serving its model.
+func (r *Registry) CompleteStream(ctx context.Context, req *CompletionRequest, callback StreamCallback) (*CompletionResponse, error) {
+	c, routed, err := r.route(req)
+	if err != nil {
+		return nil, err
+	}
+	return c.CompleteStream(ctx, routed, callback)
+}
+
+// Validate checks a request against the limits of the provider serving its model.
+func (r *Registry) Validate(req *CompletionRequest) error {
+	c, routed, err := r.route(req)
+	if err != nil {
+		return err
+	}
+	return c.Validate(routed)
+}
+
+// Name returns the registry's provider name.
+func (r *Registry) Name() string {
+	return "registry"
+}
+
+// Models returns every model served, the default provider's first.
+func (r *Registry) Models() []string {
+	var models []string
+	if c := r.Default(); c != nil {
+		models = append(models, c.Models()...)
+	}
+	for _, c := range r.clients {
+		if c == r.Default() {
+			continue
+		}
+		for _, m := range c.Models() {
+			if !slices.Contains(models, m) {
+				models = append(models, m)
+			}
+		}
+	}
+	return models
+}
+
+// route resolves the provider for a request and returns a copy naming the
+// model it will be served with.
+func (r *Registry) route(req *CompletionRequest) (Client, *CompletionRequest, error) {
+	c, model, err := r.Resolve(req.Model)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	routed := *req
+	routed.Model = model
+	return c, &routed, nil
+}
+
+// DefaultModel returns the model a client serves requests without a model
+// with: the first one it lists.
+func DefaultModel(c Client) string {
+	models := c.Models()
+	if len(models) == 0 {
+		return ""
+	}
+	return models[0]
+}
@@ -0,0 +1,173 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+func init() {
+	// Encodings ship with the binary rather than being fetched on first use
+	tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader())
+}
+
+// defaultContextWindow is assumed for models with no known context size.
+const defaultContextWindow = 8192
+
+// contextWindows maps model name prefixes to their context size in tokens.
+// Longer prefixes must precede shorter ones sharing a stem.
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"claude-", 200000},
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+}
+
+// ContextWindow returns the context size of a model in tokens.
+func ContextWindow(model string) int {
+	for _, w := range contextWindows {
+		if strings.HasPrefix(model, w.prefix) {
+			return w.tokens
+		}
+	}
+	return defaultContextWindow
+}
+
+// messageOverhead approximates the per-message framing tokens providers add.
+const messageOverhead = 4
+
+// replyOverhead approximates the tokens that prime the assistant's reply.
+const replyOverhead = 3
+
+// claudeEncoding stands in for Anthropic's tokenizer, which is not public.
+// Its counts run close to cl100k's, and an exact vocabulary beats a guess.
+const claudeEncoding = tiktoken.MODEL_CL100K_BASE
+
+var (
+	encodersMu sync.Mutex
+	encoders   = make(map[string]*tiktoken.Tiktoken)
+)
+
+// encoder returns the BPE tokenizer of a model, or nil for models with no
+// known vocabulary, such as locally served ones.
+func encoder(model string) *tiktoken.Tiktoken {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	if enc, ok := encoders[model]; ok {
+		return enc
+	}
+
+	var enc *tiktoken.Tiktoken
+	var err error
+	if strings.HasPrefix(model, "claude-") {
+		enc, err = tiktoken.GetEncoding(claudeEncoding)
+	} else {
+		enc, err = tiktoken.EncodingForModel(model)
+	}
+	if err != nil {
+		enc = nil
+	}
+	encoders[model] = enc
+	return enc
+}
+
+// CountTokens returns the number of tokens text costs with a model: exactly
+// for models with a known BPE vocabulary, approximately for the rest.
+func CountTokens(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	if enc := encoder(model); enc != nil {
+		return len(enc.EncodeOrdinary(text))
+	}
+	return approximateTokens(text)
+}
+
+// pretokenizer splits text the way cl100k-style BPE tokenizers do before
+// merging, short of the lookahead RE2 cannot express.
+var pretokenizer = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// approximateTokens approximates the number of tokens an unknown BPE
+// tokenizer produces for text. Merges rarely span pre-token boundaries, so
+// each pre-token costs one token per few ASCII characters and one per other
+// character.
+func approximateTokens(text string) int {
+	tokens := 0
+	for _, piece := range pretokenizer.FindAllString(text, -1) {
+		ascii := 0
+		for i := 0; i < len(piece); i++ {
+			if piece[i] < utf8.RuneSelf {
+				ascii++
+			}
+		}
+		tokens += max((ascii+5)/6+utf8.RuneCountInString(piece)-ascii, 1)
+	}
+	return tokens
+}
+
+// imageTokens approximates the tokens an image costs. Providers charge by
+// size; this is roughly the cost of the largest image they accept unscaled.
+const imageTokens = 1600
+
+// EstimateTokens approximates the token count of a chat message sent to a model.
+func EstimateTokens(model string, msg ChatMessage) int {
+	return estimateBlockTokens(model, msg.ContentBlocks()) + messageOverhead
+}
+
+// estimateBlockTokens approximates the token count of content blocks.
+// Thinking is not counted, as providers strip it from earlier turns.
+func estimateBlockTokens(model string, blocks []ContentBlock) int {
+	tokens := 0
+	for _, block := range blocks {
+		switch block.Type {
+		case BlockText:
+			tokens += CountTokens(model, block.Text)
+		case BlockImage:
+			tokens += imageTokens
+		case BlockToolUse:
+			tokens += CountTokens(model, block.ToolCall.Name) + CountTokens(model, string(block.ToolCall.Arguments))
+		case BlockToolResult:
+			tokens += estimateBlockTokens(model, block.Content)
+		}
+	}
+	return tokens
+}
+
+// EstimateToolTokens approximates the prompt tokens spent declaring tools.
+func EstimateToolTokens(model string, tools []Tool) int {
+	tokens := 0
+	for _, tool := range tools {
+		tokens += CountTokens(model, tool.Name) + CountTokens(model, tool.Description) + CountTokens(model, string(tool.InputSchema))
+	}
+	return tokens
+}
+
+// EstimateRequestTokens approximates the input tokens of a completion request.
+func EstimateRequestTokens(req *CompletionRequest) int {
+	tokens := replyOverhead
+	if req.System != "" {
+		tokens += EstimateTokens(req.Model, ChatMessage{Role: "system", Content: req.System})
+	}
+	for _, msg := range req.Messages {
+		tokens += EstimateTokens(req.Model, msg)
+	}
+	return tokens + EstimateToolTokens(req.Model, req.Tools) + EstimateFormatTokens(req.Model, req.ResponseFormat)
+}
+
+// EstimateFormatTokens approximates the prompt tokens spent declaring a
+// response format.
+func EstimateFormatTokens(model string, format *ResponseFormat) int {
+	if format == nil {
+		return 0
+	}
+	return CountTokens(model, format.Name) + CountTokens(model, string(format.Schema))
+}
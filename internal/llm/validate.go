@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"fmt"
+	"slices"
+)
+
+// checkModel rejects models a provider does not serve.
+func checkModel(c Client, model string) error {
+	if model == "" || slices.Contains(c.Models(), model) {
+		return nil
+	}
+	return fmt.Errorf("%w: model %q is not available from %s", ErrInvalidRequest, model, c.Name())
+}
+
+// checkRange rejects an optional setting outside [min, max].
+func checkRange(name string, value *float64, min, max float64) error {
+	if value == nil || (*value >= min && *value <= max) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s must be between %g and %g", ErrInvalidRequest, name, min, max)
+}
+
+// checkMaxTokens rejects a completion length the model cannot produce.
+func checkMaxTokens(maxTokens, limit int) error {
+	if maxTokens >= 0 && maxTokens <= limit {
+		return nil
+	}
+	return fmt.Errorf("%w: max_tokens must be between 1 and %d", ErrInvalidRequest, limit)
+}
+
+// minThinkingBudget is the smallest reasoning budget providers accept.
+const minThinkingBudget = 1024
+
+// checkThinkingBudget rejects a reasoning budget below the provider minimum
+// or one that leaves no room for the answer.
+func checkThinkingBudget(budget, maxTokens int) error {
+	if budget == 0 {
+		return nil
+	}
+	if budget < minThinkingBudget {
+		return fmt.Errorf("%w: thinking_budget must be at least %d", ErrInvalidRequest, minThinkingBudget)
+	}
+	if maxTokens > 0 && budget >= maxTokens {
+		return fmt.Errorf("%w: thinking_budget must be less than max_tokens", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// checkNoThinking rejects a reasoning budget sent to a provider without
+// extended thinking.
+func checkNoThinking(c Client, budget int) error {
+	if budget == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s does not support extended thinking", ErrInvalidRequest, c.Name())
+}
+
+// checkStopSequences rejects empty stop sequences and more than limit of them.
+func checkStopSequences(stop []string, limit int) error {
+	if limit > 0 && len(stop) > limit {
+		return fmt.Errorf("%w: at most %d stop sequences are allowed", ErrInvalidRequest, limit)
+	}
+	for _, seq := range stop {
+		if seq == "" {
+			return fmt.Errorf("%w: stop sequences cannot be empty", ErrInvalidRequest)
+		}
+	}
+	return nil
+}
@@ -59,6 +59,11 @@ func Auth(jwtSecret string) func(http.Handler) http.Handler {
 				return
 			}
 
+			if err := ValidateTenantID(claims.TenantID); err != nil {
+				http.Error(w, `{"error":"invalid tenant"}`, http.StatusUnauthorized)
+				return
+			}
+
 			// Add claims to context
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.Subject)
 			ctx = context.WithValue(ctx, TenantIDKey, claims.TenantID)
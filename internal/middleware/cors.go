@@ -1,11 +1,13 @@
 package middleware
 
 import (
+	"net/http"
+
 	"github.com/go-chi/cors"
 )
 
 // CORS returns a configured CORS middleware.
-func CORS() func(next interface{}) interface{} {
+func CORS() func(next http.Handler) http.Handler {
 	return cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
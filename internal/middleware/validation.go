@@ -1,10 +1,15 @@
 package middleware
 
 import (
+	"encoding/base64"
 	"errors"
+	"net/url"
+	"regexp"
 	"unicode/utf8"
 
 	"github.com/google/uuid"
+
+	"github.com/capitalize-ai/conversational-platform/internal/model"
 )
 
 // ValidateMessageContent validates message content.
@@ -21,6 +26,103 @@ func ValidateMessageContent(content string) error {
 	return nil
 }
 
+// maxImageBytes is the largest inline image providers accept.
+const maxImageBytes = 5 * 1024 * 1024
+
+// imageMediaTypes are the image formats every provider accepts.
+var imageMediaTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ValidateSendMessage validates the content of a message sent by a user,
+// given either as text or as text, image and attachment blocks, and its
+// response format.
+func ValidateSendMessage(req *model.SendMessageRequest) error {
+	if req.ResponseFormat != nil {
+		if err := validateResponseFormat(req.ResponseFormat); err != nil {
+			return err
+		}
+	}
+	if len(req.ContentBlocks) == 0 {
+		return ValidateMessageContent(req.Content)
+	}
+	if req.Content != "" {
+		return errors.New("content and content_blocks cannot both be set")
+	}
+	if len(req.ContentBlocks) > 100 {
+		return errors.New("content_blocks exceeds maximum length")
+	}
+
+	text := 0
+	for _, block := range req.ContentBlocks {
+		switch block.Type {
+		case model.ContentBlockText:
+			if block.Text == "" {
+				return errors.New("text blocks cannot be empty")
+			}
+			if !utf8.ValidString(block.Text) {
+				return errors.New("content must be valid UTF-8")
+			}
+			text += len(block.Text)
+		case model.ContentBlockImage:
+			if err := validateImageSource(block.Source); err != nil {
+				return err
+			}
+		case model.ContentBlockAttachment:
+			if _, err := uuid.Parse(block.AttachmentID); err != nil {
+				return errors.New("invalid attachment ID format")
+			}
+		default:
+			return errors.New("content_blocks may only contain text, image and attachment blocks")
+		}
+	}
+	if text > 100000 { // ~100KB limit
+		return errors.New("content exceeds maximum length")
+	}
+	return nil
+}
+
+// validateImageSource validates an inline image.
+func validateImageSource(source *model.ImageSource) error {
+	if source == nil || source.Type != model.ImageSourceBase64 {
+		return errors.New("image source must be base64 data")
+	}
+	if !imageMediaTypes[source.MediaType] {
+		return errors.New("image media_type must be image/jpeg, image/png, image/gif or image/webp")
+	}
+	if base64.StdEncoding.DecodedLen(len(source.Data)) > maxImageBytes {
+		return errors.New("image exceeds maximum size")
+	}
+	if _, err := base64.StdEncoding.DecodeString(source.Data); err != nil {
+		return errors.New("image data must be valid base64")
+	}
+	return nil
+}
+
+// maxSchemaBytes caps the size of a response format's schema.
+const maxSchemaBytes = 64 * 1024
+
+// validateResponseFormat validates the shape of a response format. The
+// schema itself is checked by the message service.
+func validateResponseFormat(format *model.ResponseFormat) error {
+	if format.Type != model.ResponseFormatJSONSchema || format.JSONSchema == nil {
+		return errors.New("response_format must be of type json_schema with a json_schema")
+	}
+	if !toolNamePattern.MatchString(format.JSONSchema.Name) {
+		return errors.New("response_format name must be 1-64 letters, digits, underscores or hyphens")
+	}
+	if len(format.JSONSchema.Schema) == 0 {
+		return errors.New("response_format schema is required")
+	}
+	if len(format.JSONSchema.Schema) > maxSchemaBytes {
+		return errors.New("response_format schema exceeds maximum size")
+	}
+	return nil
+}
+
 // ValidateConversationID validates a conversation ID.
 func ValidateConversationID(id string) error {
 	if _, err := uuid.Parse(id); err != nil {
@@ -37,6 +139,26 @@ func ValidateMessageID(id string) error {
 	return nil
 }
 
+// ValidateAttachmentID validates an attachment ID.
+func ValidateAttachmentID(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return errors.New("invalid attachment ID format")
+	}
+	return nil
+}
+
+// ValidateTemplateID validates a prompt template ID.
+func ValidateTemplateID(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return errors.New("invalid template ID format")
+	}
+	return nil
+}
+
+// tenantIDPattern keeps tenant IDs safe to embed in KV keys and NATS
+// subjects, where '.', '*' and '>' are token separators and wildcards.
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 // ValidateTenantID validates a tenant ID.
 func ValidateTenantID(id string) error {
 	if len(id) == 0 {
@@ -45,6 +167,9 @@ func ValidateTenantID(id string) error {
 	if len(id) > 64 {
 		return errors.New("tenant ID exceeds maximum length")
 	}
+	if !tenantIDPattern.MatchString(id) {
+		return errors.New("tenant ID may only contain letters, digits, underscores and hyphens")
+	}
 	return nil
 }
 
@@ -58,3 +183,74 @@ func ValidateTitle(title string) error {
 	}
 	return nil
 }
+
+// ValidateSystemPrompt validates a conversation system prompt.
+func ValidateSystemPrompt(prompt string) error {
+	if len(prompt) > 100000 { // ~100KB limit
+		return errors.New("system prompt exceeds maximum length")
+	}
+	if !utf8.ValidString(prompt) {
+		return errors.New("system prompt must be valid UTF-8")
+	}
+	return nil
+}
+
+// ValidateTemplateName validates a prompt template name.
+func ValidateTemplateName(name string) error {
+	if len(name) == 0 {
+		return errors.New("name cannot be empty")
+	}
+	if len(name) > 256 {
+		return errors.New("name exceeds maximum length")
+	}
+	if !utf8.ValidString(name) {
+		return errors.New("name must be valid UTF-8")
+	}
+	return nil
+}
+
+// ValidateFilename validates an attachment's file name.
+func ValidateFilename(name string) error {
+	if name == "" || name == "." || name == "/" {
+		return errors.New("file name cannot be empty")
+	}
+	if len(name) > 256 {
+		return errors.New("file name exceeds maximum length")
+	}
+	if !utf8.ValidString(name) {
+		return errors.New("file name must be valid UTF-8")
+	}
+	return nil
+}
+
+// toolNamePattern matches the tool names every provider accepts.
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ValidateToolName validates a tool name.
+func ValidateToolName(name string) error {
+	if !toolNamePattern.MatchString(name) {
+		return errors.New("tool name must be 1-64 letters, digits, underscores or hyphens")
+	}
+	return nil
+}
+
+// ValidateToolDescription validates a tool description.
+func ValidateToolDescription(description string) error {
+	if len(description) > 4096 {
+		return errors.New("description exceeds maximum length")
+	}
+	if !utf8.ValidString(description) {
+		return errors.New("description must be valid UTF-8")
+	}
+	return nil
+}
+
+// ValidateWebhookURL validates a tool webhook URL. Tool calls carry
+// conversation content, so webhooks must be served over https.
+func ValidateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.New("webhook_url must be an absolute https URL")
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Attachment is a file uploaded to a conversation. The content lives in the
+// tenant's object store bucket under the attachment ID.
+type Attachment struct {
+	ID             string    `json:"id"`
+	TenantID       string    `json:"tenant_id"`
+	ConversationID string    `json:"conversation_id"`
+	Filename       string    `json:"filename"`
+	ContentType    string    `json:"content_type"`
+	Size           int64     `json:"size"`
+	SHA256         string    `json:"sha256"`
+	CreatedAt      time.Time `json:"created_at"`
+}
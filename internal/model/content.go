@@ -0,0 +1,163 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ContentBlockType identifies the kind of a content block.
+type ContentBlockType string
+
+const (
+	ContentBlockText             ContentBlockType = "text"
+	ContentBlockImage            ContentBlockType = "image"
+	ContentBlockToolUse          ContentBlockType = "tool_use"
+	ContentBlockToolResult       ContentBlockType = "tool_result"
+	ContentBlockThinking         ContentBlockType = "thinking"
+	ContentBlockAttachment       ContentBlockType = "attachment"
+	ContentBlockRedactedThinking ContentBlockType = "redacted_thinking"
+)
+
+// ContentBlock is one typed part of a message's content. Only the fields of
+// its type are set; the JSON shape follows Anthropic's content blocks.
+type ContentBlock struct {
+	Type ContentBlockType `json:"type"`
+
+	// Text blocks, with the sources the model cited for them
+	Text      string     `json:"text,omitempty"`
+	Citations []Citation `json:"citations,omitempty"`
+
+	// Image blocks
+	Source *ImageSource `json:"source,omitempty"`
+
+	// Tool use blocks
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// Tool result blocks. Content holds the result's text and image blocks.
+	ToolUseID string         `json:"tool_use_id,omitempty"`
+	Content   []ContentBlock `json:"content,omitempty"`
+	IsError   bool           `json:"is_error,omitempty"`
+
+	// Attachment blocks reference an uploaded file, which is inlined when the
+	// message is sent to the LLM. Name and MediaType describe the file.
+	AttachmentID string `json:"attachment_id,omitempty"`
+	MediaType    string `json:"media_type,omitempty"`
+
+	// Thinking blocks. The signature lets the provider verify the thinking
+	// when it is sent back. Redacted thinking blocks hold the provider's
+	// encrypted reasoning in Data.
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+// ImageSource is an inline image.
+type ImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// ImageSourceBase64 is the only image source type, base64 encoded data.
+const ImageSourceBase64 = "base64"
+
+// Citation is a source passage a text block relies on.
+type Citation struct {
+	Type          string `json:"type"`
+	CitedText     string `json:"cited_text"`
+	DocumentIndex int    `json:"document_index,omitempty"`
+	DocumentTitle string `json:"document_title,omitempty"`
+	URL           string `json:"url,omitempty"`
+	StartIndex    int    `json:"start_index,omitempty"`
+	EndIndex      int    `json:"end_index,omitempty"`
+}
+
+// Blocks returns the message's content as typed blocks. Messages stored
+// before content blocks existed, and plain text messages, have them built
+// from Content and the tool call fields.
+func (m *Message) Blocks() []ContentBlock {
+	if len(m.ContentBlocks) > 0 {
+		return m.ContentBlocks
+	}
+
+	if m.Role == RoleTool {
+		return []ContentBlock{{
+			Type:      ContentBlockToolResult,
+			ToolUseID: m.ToolCallID,
+			Content:   []ContentBlock{{Type: ContentBlockText, Text: m.Content}},
+			IsError:   m.IsError,
+		}}
+	}
+
+	var blocks []ContentBlock
+	if m.Content != "" || len(m.ToolCalls) == 0 {
+		blocks = append(blocks, ContentBlock{Type: ContentBlockText, Text: m.Content})
+	}
+	for _, call := range m.ToolCalls {
+		blocks = append(blocks, ContentBlock{
+			Type:  ContentBlockToolUse,
+			ID:    call.ID,
+			Name:  call.Name,
+			Input: call.Arguments,
+		})
+	}
+	return blocks
+}
+
+// SetBlocks sets the message's content blocks and derives Content and the
+// tool call fields from them, so clients that predate content blocks still
+// see the text and tool calls. A single text block is stored as Content alone.
+func (m *Message) SetBlocks(blocks []ContentBlock) {
+	m.ContentBlocks = blocks
+	if len(blocks) == 1 && blocks[0].Type == ContentBlockText && len(blocks[0].Citations) == 0 {
+		m.ContentBlocks = nil
+	}
+
+	m.Content = TextContent(blocks)
+	m.ToolCalls = nil
+	for _, block := range blocks {
+		switch block.Type {
+		case ContentBlockToolUse:
+			m.ToolCalls = append(m.ToolCalls, ToolCall{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: block.Input,
+			})
+		case ContentBlockToolResult:
+			m.Content = TextContent(block.Content)
+			m.ToolCallID = block.ToolUseID
+			m.IsError = block.IsError
+		}
+	}
+}
+
+// WithoutThinking returns the message with its thinking blocks removed, or
+// the message itself if it has none.
+func (m *Message) WithoutThinking() *Message {
+	blocks := make([]ContentBlock, 0, len(m.ContentBlocks))
+	for _, block := range m.ContentBlocks {
+		if block.Type != ContentBlockThinking && block.Type != ContentBlockRedactedThinking {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) == len(m.ContentBlocks) {
+		return m
+	}
+
+	redacted := *m
+	redacted.ContentBlocks = blocks
+	return &redacted
+}
+
+// TextContent joins the text of a message's text blocks.
+func TextContent(blocks []ContentBlock) string {
+	var texts []string
+	for _, block := range blocks {
+		if block.Type == ContentBlockText {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, "")
+}
@@ -17,18 +17,63 @@ type Conversation struct {
 	MessageCount int               `json:"message_count,omitempty"`
 	LastMessage  *Message          `json:"last_message,omitempty"`
 	Deleted      bool              `json:"deleted,omitempty"`
+
+	// Profile holds the generation settings applied to every turn.
+	Profile *GenerationProfile `json:"profile,omitempty"`
+
+	// Template records the template version and variables the profile's
+	// system prompt was rendered from.
+	Template *TemplateRef `json:"template,omitempty"`
+
+	// LastSequence is the highest stream sequence projected into this record.
+	LastSequence uint64 `json:"last_sequence,omitempty"`
+}
+
+// GenerationProfile configures how replies in a conversation are generated.
+// Unset fields fall back to the provider defaults.
+type GenerationProfile struct {
+	SystemPrompt  string   `json:"system_prompt,omitempty"`
+	Model         string   `json:"model,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	MaxTokens     int      `json:"max_tokens,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
+
+	// ThinkingBudget opts the conversation into extended thinking, letting
+	// the model spend up to this many of its max_tokens reasoning.
+	ThinkingBudget int `json:"thinking_budget,omitempty"`
+}
+
+// LifecycleAction identifies a change to a conversation's metadata.
+type LifecycleAction string
+
+const (
+	LifecycleCreated LifecycleAction = "created"
+	LifecycleUpdated LifecycleAction = "updated"
+	LifecycleDeleted LifecycleAction = "deleted"
+)
+
+// ConversationLifecycleEvent records a conversation metadata change on the stream
+// so the conversation index can be rebuilt from the log.
+type ConversationLifecycleEvent struct {
+	Action       LifecycleAction `json:"action"`
+	Conversation Conversation    `json:"conversation"`
+	CreatedAt    time.Time       `json:"created_at"`
 }
 
 // CreateConversationRequest is the request to create a new conversation.
 type CreateConversationRequest struct {
-	Title    string            `json:"title"`
-	Metadata map[string]string `json:"metadata,omitempty"`
+	Title    string             `json:"title"`
+	Metadata map[string]string  `json:"metadata,omitempty"`
+	Profile  *GenerationProfile `json:"profile,omitempty"`
+	Template *TemplateRef       `json:"template,omitempty"`
 }
 
 // UpdateConversationRequest is the request to update a conversation.
 type UpdateConversationRequest struct {
-	Title    string            `json:"title,omitempty"`
-	Metadata map[string]string `json:"metadata,omitempty"`
+	Title    string             `json:"title,omitempty"`
+	Metadata map[string]string  `json:"metadata,omitempty"`
+	Profile  *GenerationProfile `json:"profile,omitempty"`
 }
 
 // ListConversationsResponse is the response for listing conversations.
@@ -12,6 +12,14 @@ const (
 	EventTypeCancel    EventType = "cancel"
 	EventTypeRateLimit EventType = "rate_limit"
 	EventTypeTimeout   EventType = "timeout"
+	EventTypeFailover  EventType = "failover"
+
+	// Progress of server-side tool execution within a generation
+	EventTypeToolCall   EventType = "tool_call"
+	EventTypeToolResult EventType = "tool_result"
+
+	// A reply did not match its response format's schema and is generated again
+	EventTypeSchemaRetry EventType = "schema_retry"
 )
 
 // ConversationEvent represents an event in a conversation.
@@ -21,6 +29,8 @@ type ConversationEvent struct {
 	TenantID       string         `json:"tenant_id"`
 	Type           EventType      `json:"type"`
 	Reason         string         `json:"reason"`
+	Code           string         `json:"code,omitempty"`
+	RetryAfter     int            `json:"retry_after,omitempty"`
 	Metadata       map[string]any `json:"metadata,omitempty"`
 	CreatedAt      time.Time      `json:"created_at"`
 	Sequence       uint64         `json:"sequence,omitempty"`
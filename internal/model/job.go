@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// GenerationJob asks a worker to generate the assistant reply to a user message.
+type GenerationJob struct {
+	ID                 string    `json:"id"`
+	TenantID           string    `json:"tenant_id"`
+	ConversationID     string    `json:"conversation_id"`
+	UserMessageID      string    `json:"user_message_id"`
+	UserSequence       uint64    `json:"user_sequence"`
+	AssistantMessageID string    `json:"assistant_message_id"`
+	Model              string    `json:"model,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+
+	// ResponseFormat, if set, asks for the reply as JSON matching a schema.
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// Attempt is the delivery count of the job, set by the worker running it.
+	Attempt int `json:"-"`
+}
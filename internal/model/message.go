@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -21,17 +22,38 @@ type Message struct {
 	ConversationID string `json:"conversation_id"`
 	TenantID       string `json:"tenant_id"`
 
-	// Content
-	Role    Role   `json:"role"`
-	Content string `json:"content"`
+	// Content. Content holds the text; ContentBlocks, when present, is the
+	// full typed content including images and tool blocks.
+	Role          Role           `json:"role"`
+	Content       string         `json:"content"`
+	ContentBlocks []ContentBlock `json:"content_blocks,omitempty"`
+
+	// Tool calls requested by an assistant message, and on tool messages the
+	// call they answer
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	ToolName   string     `json:"tool_name,omitempty"`
+	IsError    bool       `json:"is_error,omitempty"`
 
 	// LLM Metadata (nullable for non-assistant messages)
+	Provider   *string `json:"provider,omitempty"`
 	Model      *string `json:"model,omitempty"`
 	TokensIn   *int    `json:"tokens_in,omitempty"`
 	TokensOut  *int    `json:"tokens_out,omitempty"`
 	LatencyMs  *int64  `json:"latency_ms,omitempty"`
 	StopReason *string `json:"stop_reason,omitempty"`
 
+	// Prompt cache usage, not included in TokensIn
+	CacheReadTokens  *int `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens *int `json:"cache_write_tokens,omitempty"`
+
+	// TokensEstimated is set when the token counts are local estimates
+	// rather than usage reported by the provider.
+	TokensEstimated bool `json:"tokens_estimated,omitempty"`
+
+	// SchemaValidation is set on replies generated with a response format.
+	SchemaValidation *SchemaValidation `json:"schema_validation,omitempty"`
+
 	// Timestamps
 	CreatedAt     time.Time  `json:"created_at"`
 	StreamStarted *time.Time `json:"stream_started,omitempty"`
@@ -41,11 +63,50 @@ type Message struct {
 	Sequence uint64 `json:"sequence,omitempty"`
 }
 
-// SendMessageRequest is the request to send a new message.
+// SendMessageRequest is the request to send a new message. Content may be
+// replaced by ContentBlocks to send text and images. ResponseFormat asks for
+// the reply as JSON matching a schema.
 type SendMessageRequest struct {
-	Content string `json:"content"`
-	Model   string `json:"model,omitempty"`
-	Stream  bool   `json:"stream"`
+	Content        string          `json:"content"`
+	ContentBlocks  []ContentBlock  `json:"content_blocks,omitempty"`
+	Model          string          `json:"model,omitempty"`
+	Stream         bool            `json:"stream"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormatJSONSchema is the only response format type, JSON matching
+// a JSON Schema.
+const ResponseFormatJSONSchema = "json_schema"
+
+// ResponseFormat constrains a reply to structured output. It follows
+// OpenAI's response_format shape.
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema is a named JSON Schema a reply must match. The schema's root
+// must be an object.
+type JSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// SchemaValidation records whether a reply matched its response format's
+// schema, after how many attempts, and if not, why.
+type SchemaValidation struct {
+	Valid    bool     `json:"valid"`
+	Attempts int      `json:"attempts"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// StructuredOutput returns the JSON a reply generated with a response format
+// holds, or nil if it did not match the schema.
+func (m *Message) StructuredOutput() json.RawMessage {
+	if m.SchemaValidation == nil || !m.SchemaValidation.Valid {
+		return nil
+	}
+	return json.RawMessage(m.Content)
 }
 
 // SendMessageResponse is the response after sending a message.
@@ -62,16 +123,37 @@ type ListMessagesResponse struct {
 	StreamActive bool      `json:"stream_active"`
 }
 
-// TokenEvent represents a streaming token event.
+// TokenEvent represents a streaming token event. MessageID is the ID the
+// assistant message will be persisted under, so clients joining mid-generation
+// can dedupe tokens by (message_id, index). Attempt increases whenever the
+// message is streamed again, by a redelivered job or to retry a reply that did
+// not match its response format; clients drop the tokens of earlier attempts
+// and resume from the generation buffer. Thinking deltas are indexed
+// separately and are not buffered for resuming clients.
 type TokenEvent struct {
-	Token string `json:"token"`
-	Index int    `json:"index"`
+	MessageID string `json:"message_id,omitempty"`
+	Attempt   int    `json:"attempt,omitempty"`
+	Token     string `json:"token"`
+	Index     int    `json:"index"`
+	Thinking  bool   `json:"thinking,omitempty"`
+}
+
+// PartialGeneration is the buffered output of an assistant message that is
+// still being generated, kept so reconnecting clients can resume by token index.
+type PartialGeneration struct {
+	MessageID string    `json:"message_id"`
+	Attempt   int       `json:"attempt,omitempty"`
+	Tokens    []string  `json:"tokens"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// MessageCompleteEvent represents a message completion event.
+// MessageCompleteEvent represents a message completion event. Output is the
+// parsed reply of a generation with a valid response format.
 type MessageCompleteEvent struct {
-	Message  Message `json:"message"`
-	Sequence uint64  `json:"sequence"`
+	Message  Message         `json:"message"`
+	Sequence uint64          `json:"sequence"`
+	Output   json.RawMessage `json:"output,omitempty"`
 }
 
 // ErrorEvent represents an error event.
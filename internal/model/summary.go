@@ -0,0 +1,11 @@
+package model
+
+// Summary is a rolling summary of a conversation's earlier history. It is a
+// system message that stands in for every message from FromSequence through
+// ThroughSequence when building the LLM context.
+type Summary struct {
+	Message
+
+	FromSequence    uint64 `json:"from_sequence"`
+	ThroughSequence uint64 `json:"through_sequence"`
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+)
+
+// PromptTemplate is a tenant's reusable system prompt. Content is a
+// text/template body whose placeholders ({{.name}}) are filled from the
+// variables supplied when a conversation is created from it. Every content
+// change creates a new version; earlier versions stay readable.
+type PromptTemplate struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Content     string    `json:"content"`
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Deleted     bool      `json:"deleted,omitempty"`
+}
+
+// TemplateRef selects a template version and the variable values to render it with.
+type TemplateRef struct {
+	ID        string            `json:"id"`
+	Version   int               `json:"version,omitempty"` // latest if zero
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// CreateTemplateRequest is the request to create a prompt template.
+type CreateTemplateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Content     string `json:"content"`
+}
+
+// UpdateTemplateRequest is the request to update a prompt template.
+type UpdateTemplateRequest struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Content     string `json:"content,omitempty"`
+}
+
+// ListTemplatesResponse is the response for listing prompt templates.
+type ListTemplatesResponse struct {
+	Templates []PromptTemplate `json:"templates"`
+	Total     int              `json:"total"`
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Tool is a function a tenant lets the LLM call. InputSchema is the JSON
+// Schema of its arguments. Calls are executed server-side by POSTing them to
+// the tool's webhook.
+type Tool struct {
+	TenantID    string          `json:"tenant_id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+	WebhookURL  string          `json:"webhook_url"`
+	Secret      string          `json:"secret,omitempty"` // signs webhook requests; never returned by the API
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// ToolCall is a tool invocation requested by the LLM.
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ToolWebhookRequest is the body POSTed to a tool's webhook. The response
+// body is returned to the LLM as the call's result.
+type ToolWebhookRequest struct {
+	TenantID       string          `json:"tenant_id"`
+	ConversationID string          `json:"conversation_id"`
+	CallID         string          `json:"call_id"`
+	Tool           string          `json:"tool"`
+	Arguments      json.RawMessage `json:"arguments"`
+}
+
+// CreateToolRequest is the request to register a tool.
+type CreateToolRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+	WebhookURL  string          `json:"webhook_url"`
+	Secret      string          `json:"secret,omitempty"`
+}
+
+// UpdateToolRequest is the request to update a tool.
+type UpdateToolRequest struct {
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+	WebhookURL  string          `json:"webhook_url,omitempty"`
+	Secret      string          `json:"secret,omitempty"`
+}
+
+// ListToolsResponse is the response for listing tools.
+type ListToolsResponse struct {
+	Tools []Tool `json:"tools"`
+	Total int    `json:"total"`
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
 
 	"github.com/capitalize-ai/conversational-platform/pkg/logger"
 )
@@ -38,7 +39,7 @@ func Connect(ctx context.Context, cfg Config, log *logger.Logger) (*Client, erro
 		nats.ReconnectWait(2 * time.Second),
 		nats.ReconnectBufSize(8 * 1024 * 1024),
 		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
-			log.Warn("NATS disconnected", logger.Global().With().Logger.Sugar().Desugar().Check(0, "").Entry)
+			log.Warn("NATS disconnected", zap.Error(err))
 		}),
 		nats.ReconnectHandler(func(nc *nats.Conn) {
 			log.Info("NATS reconnected")
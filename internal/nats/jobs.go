@@ -0,0 +1,172 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+)
+
+const (
+	// JobStreamName is the name of the generation work-queue stream.
+	JobStreamName = "GENERATION_JOBS"
+
+	// JobSubjectPrefix is the prefix for generation job subjects.
+	JobSubjectPrefix = "jobs.generate"
+
+	// JobMaxAge is how long a generation job may wait in the queue.
+	JobMaxAge = time.Hour
+)
+
+// JobSubject returns the subject a conversation's generation jobs are queued on.
+func JobSubject(tenantID, conversationID string) string {
+	return fmt.Sprintf("%s.%s.%s", JobSubjectPrefix, tenantID, conversationID)
+}
+
+// EnsureJobStream ensures the generation work-queue stream exists. Each job is
+// removed from the stream once a worker acknowledges it.
+func (m *StreamManager) EnsureJobStream(ctx context.Context) error {
+	_, err := m.client.JetStream().CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:        JobStreamName,
+		Subjects:    []string{fmt.Sprintf("%s.>", JobSubjectPrefix)},
+		Retention:   jetstream.WorkQueuePolicy,
+		MaxAge:      JobMaxAge,
+		Storage:     jetstream.FileStorage,
+		Replicas:    1,
+		Description: "Pending assistant generation jobs",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create job stream: %w", err)
+	}
+
+	return nil
+}
+
+// PublishJob queues a generation job. The job ID doubles as the JetStream
+// message ID so a retried publish is deduplicated.
+func (m *StreamManager) PublishJob(ctx context.Context, job *model.GenerationJob) (uint64, error) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	ack, err := m.client.JetStream().Publish(ctx, JobSubject(job.TenantID, job.ConversationID), data, jetstream.WithMsgID(job.ID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish job: %w", err)
+	}
+
+	return ack.Sequence, nil
+}
+
+// PendingJob returns the newest generation job of a conversation that no
+// worker has acknowledged yet, or ErrNotFound if none is queued or running.
+func (m *StreamManager) PendingJob(ctx context.Context, tenantID, conversationID string) (*model.GenerationJob, error) {
+	stream, err := m.client.JetStream().Stream(ctx, JobStreamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job stream: %w", err)
+	}
+
+	msg, err := stream.GetLastMsgForSubject(ctx, JobSubject(tenantID, conversationID))
+	if err != nil {
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get pending job: %w", err)
+	}
+
+	var job model.GenerationJob
+	if err := json.Unmarshal(msg.Data, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// JobDelivery is a generation job delivered to a worker.
+type JobDelivery struct {
+	Job model.GenerationJob
+	msg jetstream.Msg
+}
+
+// Ack marks the job as done and removes it from the queue.
+func (d *JobDelivery) Ack() error {
+	return d.msg.Ack()
+}
+
+// Nak asks for the job to be redelivered after delay.
+func (d *JobDelivery) Nak(delay time.Duration) error {
+	return d.msg.NakWithDelay(delay)
+}
+
+// Term stops the job from ever being redelivered.
+func (d *JobDelivery) Term(reason string) error {
+	return d.msg.TermWithReason(reason)
+}
+
+// InProgress resets the ack deadline while the job is still being worked on.
+func (d *JobDelivery) InProgress() error {
+	return d.msg.InProgress()
+}
+
+// NumDelivered returns how many times the job has been delivered, including this one.
+func (d *JobDelivery) NumDelivered() uint64 {
+	meta, err := d.msg.Metadata()
+	if err != nil {
+		return 1
+	}
+	return meta.NumDelivered
+}
+
+// JobConsumerConfig configures the durable consumer generation workers share.
+type JobConsumerConfig struct {
+	// Durable is the consumer name shared by every replica.
+	Durable string
+
+	// AckWait is how long a job may go without an ack or progress signal
+	// before it is redelivered to another worker.
+	AckWait time.Duration
+
+	// MaxDeliver bounds the number of delivery attempts per job.
+	MaxDeliver int
+
+	// MaxBuffered bounds the number of jobs pulled ahead of the handler.
+	MaxBuffered int
+}
+
+// JobHandler processes a delivered generation job.
+type JobHandler func(delivery *JobDelivery)
+
+// ConsumeJobs delivers queued generation jobs to handler through a durable
+// consumer. The handler is called sequentially; it may hand jobs off to other
+// goroutines. The returned function stops delivery.
+func (m *StreamManager) ConsumeJobs(ctx context.Context, cfg JobConsumerConfig, handler JobHandler) (func(), error) {
+	consumer, err := m.client.JetStream().CreateOrUpdateConsumer(ctx, JobStreamName, jetstream.ConsumerConfig{
+		Durable:    cfg.Durable,
+		AckPolicy:  jetstream.AckExplicitPolicy,
+		AckWait:    cfg.AckWait,
+		MaxDeliver: cfg.MaxDeliver,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job consumer: %w", err)
+	}
+
+	consumeCtx, err := consumer.Consume(func(msg jetstream.Msg) {
+		delivery := &JobDelivery{msg: msg}
+		if err := json.Unmarshal(msg.Data(), &delivery.Job); err != nil {
+			// A job that cannot be decoded will never succeed
+			msg.TermWithReason("malformed job")
+			return
+		}
+		handler(delivery)
+	}, jetstream.PullMaxMessages(cfg.MaxBuffered))
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume jobs: %w", err)
+	}
+
+	return consumeCtx.Stop, nil
+}
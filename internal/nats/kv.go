@@ -0,0 +1,598 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+)
+
+const (
+	// ConversationBucket is the name of the KV bucket holding conversation metadata.
+	ConversationBucket = "CONVERSATION_INDEX"
+
+	// CheckpointBucket is the name of the KV bucket holding projector checkpoints.
+	CheckpointBucket = "PROJECTOR_CHECKPOINTS"
+
+	// GenerationBucket is the name of the KV bucket buffering in-progress generations.
+	GenerationBucket = "GENERATIONS"
+
+	// TemplateBucket is the name of the KV bucket holding prompt templates.
+	TemplateBucket = "PROMPT_TEMPLATES"
+
+	// ToolBucket is the name of the KV bucket holding tenant tool definitions.
+	ToolBucket = "TOOLS"
+
+	// CancelBucket is the name of the KV bucket marking cancelled generation jobs.
+	CancelBucket = "CANCELLED_JOBS"
+)
+
+var (
+	// ErrNotFound is returned when a key does not exist in a bucket.
+	ErrNotFound = errors.New("key not found")
+
+	// ErrRevisionMismatch is returned when a write is attempted against a stale revision.
+	ErrRevisionMismatch = errors.New("revision mismatch")
+)
+
+// ConversationStore persists conversations in a JetStream KV bucket.
+// Keys are "{tenant}.{conversation}" so a tenant's conversations can be
+// listed with a single wildcard watch.
+type ConversationStore struct {
+	kv jetstream.KeyValue
+}
+
+// NewConversationStore creates or binds to the conversation KV bucket.
+func NewConversationStore(ctx context.Context, client *Client) (*ConversationStore, error) {
+	kv, err := client.JetStream().CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      ConversationBucket,
+		Description: "Conversation metadata keyed by tenant and conversation ID",
+		History:     1,
+		Storage:     jetstream.FileStorage,
+		Replicas:    1,
+		Compression: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create conversation bucket: %w", err)
+	}
+
+	return &ConversationStore{kv: kv}, nil
+}
+
+// ConversationKey returns the KV key for a conversation.
+func ConversationKey(tenantID, conversationID string) string {
+	return fmt.Sprintf("%s.%s", tenantID, conversationID)
+}
+
+// Create stores a new conversation and returns its revision.
+func (s *ConversationStore) Create(ctx context.Context, conv *model.Conversation) (uint64, error) {
+	data, err := json.Marshal(conv)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+
+	rev, err := s.kv.Create(ctx, ConversationKey(conv.TenantID, conv.ID), data)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return 0, ErrRevisionMismatch
+		}
+		return 0, fmt.Errorf("failed to create conversation: %w", err)
+	}
+
+	return rev, nil
+}
+
+// Get retrieves a conversation along with its current revision.
+func (s *ConversationStore) Get(ctx context.Context, tenantID, conversationID string) (*model.Conversation, uint64, error) {
+	entry, err := s.kv.Get(ctx, ConversationKey(tenantID, conversationID))
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return nil, 0, ErrNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	var conv model.Conversation
+	if err := json.Unmarshal(entry.Value(), &conv); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal conversation: %w", err)
+	}
+
+	return &conv, entry.Revision(), nil
+}
+
+// List returns every conversation stored for a tenant.
+func (s *ConversationStore) List(ctx context.Context, tenantID string) ([]model.Conversation, error) {
+	watcher, err := s.kv.Watch(ctx, ConversationKey(tenantID, "*"), jetstream.IgnoreDeletes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch conversations: %w", err)
+	}
+	defer watcher.Stop()
+
+	var convs []model.Conversation
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case entry := <-watcher.Updates():
+			// A nil entry marks the end of the initial values.
+			if entry == nil {
+				return convs, nil
+			}
+
+			var conv model.Conversation
+			if err := json.Unmarshal(entry.Value(), &conv); err != nil {
+				continue
+			}
+			convs = append(convs, conv)
+		}
+	}
+}
+
+// Update writes a conversation if the stored revision still matches.
+func (s *ConversationStore) Update(ctx context.Context, conv *model.Conversation, revision uint64) (uint64, error) {
+	data, err := json.Marshal(conv)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+
+	rev, err := s.kv.Update(ctx, ConversationKey(conv.TenantID, conv.ID), data, revision)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return 0, ErrRevisionMismatch
+		}
+		return 0, fmt.Errorf("failed to update conversation: %w", err)
+	}
+
+	return rev, nil
+}
+
+// CheckpointStore persists the last processed stream sequence of named projectors.
+type CheckpointStore struct {
+	kv jetstream.KeyValue
+}
+
+// NewCheckpointStore creates or binds to the checkpoint KV bucket.
+func NewCheckpointStore(ctx context.Context, client *Client) (*CheckpointStore, error) {
+	kv, err := client.JetStream().CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      CheckpointBucket,
+		Description: "Last processed CONVERSATIONS sequence per projector",
+		History:     1,
+		Storage:     jetstream.FileStorage,
+		Replicas:    1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint bucket: %w", err)
+	}
+
+	return &CheckpointStore{kv: kv}, nil
+}
+
+// Load returns the checkpointed sequence for a projector, or zero if none exists.
+func (s *CheckpointStore) Load(ctx context.Context, name string) (uint64, error) {
+	entry, err := s.kv.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+
+	seq, err := strconv.ParseUint(string(entry.Value()), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid checkpoint %q: %w", entry.Value(), err)
+	}
+
+	return seq, nil
+}
+
+// Save records the last processed sequence for a projector.
+func (s *CheckpointStore) Save(ctx context.Context, name string, sequence uint64) error {
+	if _, err := s.kv.PutString(ctx, name, strconv.FormatUint(sequence, 10)); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// GenerationBuffer holds the partial output of in-progress generations, one
+// entry per assistant message under "{tenant}.{conversation}.{message}", so
+// generations running at once for a conversation do not overwrite each other.
+// Entries expire after the bucket TTL so abandoned generations clean
+// themselves up.
+type GenerationBuffer struct {
+	kv jetstream.KeyValue
+}
+
+// NewGenerationBuffer creates or binds to the generation KV bucket.
+func NewGenerationBuffer(ctx context.Context, client *Client, ttl time.Duration) (*GenerationBuffer, error) {
+	kv, err := client.JetStream().CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      GenerationBucket,
+		Description: "Partial assistant output of in-progress generations",
+		History:     1,
+		TTL:         ttl,
+		Storage:     jetstream.MemoryStorage,
+		Replicas:    1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create generation bucket: %w", err)
+	}
+
+	return &GenerationBuffer{kv: kv}, nil
+}
+
+// GenerationKey returns the KV key of the generation of an assistant message.
+func GenerationKey(tenantID, conversationID, messageID string) string {
+	return fmt.Sprintf("%s.%s.%s", tenantID, conversationID, messageID)
+}
+
+// Put stores the current partial output of a generation.
+func (b *GenerationBuffer) Put(ctx context.Context, tenantID, conversationID string, partial *model.PartialGeneration) error {
+	data, err := json.Marshal(partial)
+	if err != nil {
+		return fmt.Errorf("failed to marshal partial generation: %w", err)
+	}
+
+	if _, err := b.kv.Put(ctx, GenerationKey(tenantID, conversationID, partial.MessageID), data); err != nil {
+		return fmt.Errorf("failed to store partial generation: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the partial output of the generation of an assistant message, or
+// ErrNotFound if it is not in progress.
+func (b *GenerationBuffer) Get(ctx context.Context, tenantID, conversationID, messageID string) (*model.PartialGeneration, error) {
+	entry, err := b.kv.Get(ctx, GenerationKey(tenantID, conversationID, messageID))
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get partial generation: %w", err)
+	}
+
+	var partial model.PartialGeneration
+	if err := json.Unmarshal(entry.Value(), &partial); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal partial generation: %w", err)
+	}
+
+	return &partial, nil
+}
+
+// Delete removes the partial generation of an assistant message.
+func (b *GenerationBuffer) Delete(ctx context.Context, tenantID, conversationID, messageID string) error {
+	if err := b.kv.Purge(ctx, GenerationKey(tenantID, conversationID, messageID)); err != nil {
+		return fmt.Errorf("failed to delete partial generation: %w", err)
+	}
+	return nil
+}
+
+// TemplateStore persists prompt templates in a JetStream KV bucket. The
+// current version of a template lives under "{tenant}.{template}" and every
+// version is also kept, immutable, under "{tenant}.{template}.{version}".
+type TemplateStore struct {
+	kv jetstream.KeyValue
+}
+
+// NewTemplateStore creates or binds to the template KV bucket.
+func NewTemplateStore(ctx context.Context, client *Client) (*TemplateStore, error) {
+	kv, err := client.JetStream().CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      TemplateBucket,
+		Description: "Prompt templates keyed by tenant, template and version",
+		History:     1,
+		Storage:     jetstream.FileStorage,
+		Replicas:    1,
+		Compression: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create template bucket: %w", err)
+	}
+
+	return &TemplateStore{kv: kv}, nil
+}
+
+// TemplateKey returns the KV key of the current version of a template.
+func TemplateKey(tenantID, templateID string) string {
+	return fmt.Sprintf("%s.%s", tenantID, templateID)
+}
+
+// TemplateVersionKey returns the KV key of one version of a template.
+func TemplateVersionKey(tenantID, templateID string, version int) string {
+	return fmt.Sprintf("%s.%s.%d", tenantID, templateID, version)
+}
+
+// Create stores a new template and its first version, returning the revision
+// of the current record.
+func (s *TemplateStore) Create(ctx context.Context, tpl *model.PromptTemplate) (uint64, error) {
+	data, err := json.Marshal(tpl)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal template: %w", err)
+	}
+
+	rev, err := s.kv.Create(ctx, TemplateKey(tpl.TenantID, tpl.ID), data)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return 0, ErrRevisionMismatch
+		}
+		return 0, fmt.Errorf("failed to create template: %w", err)
+	}
+
+	if err := s.putVersion(ctx, tpl, data); err != nil {
+		return 0, err
+	}
+
+	return rev, nil
+}
+
+// Get retrieves the current version of a template along with its revision.
+func (s *TemplateStore) Get(ctx context.Context, tenantID, templateID string) (*model.PromptTemplate, uint64, error) {
+	return s.get(ctx, TemplateKey(tenantID, templateID))
+}
+
+// GetVersion retrieves a specific version of a template.
+func (s *TemplateStore) GetVersion(ctx context.Context, tenantID, templateID string, version int) (*model.PromptTemplate, error) {
+	tpl, _, err := s.get(ctx, TemplateVersionKey(tenantID, templateID, version))
+	if !errors.Is(err, ErrNotFound) {
+		return tpl, err
+	}
+
+	// The version record is written after the current one, so a crash in
+	// between leaves the newest version only in the current record
+	current, _, err := s.Get(ctx, tenantID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Version != version {
+		return nil, ErrNotFound
+	}
+
+	return current, nil
+}
+
+// List returns the current version of every template stored for a tenant.
+func (s *TemplateStore) List(ctx context.Context, tenantID string) ([]model.PromptTemplate, error) {
+	watcher, err := s.kv.Watch(ctx, TemplateKey(tenantID, "*"), jetstream.IgnoreDeletes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch templates: %w", err)
+	}
+	defer watcher.Stop()
+
+	var templates []model.PromptTemplate
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case entry := <-watcher.Updates():
+			// A nil entry marks the end of the initial values.
+			if entry == nil {
+				return templates, nil
+			}
+
+			var tpl model.PromptTemplate
+			if err := json.Unmarshal(entry.Value(), &tpl); err != nil {
+				continue
+			}
+			templates = append(templates, tpl)
+		}
+	}
+}
+
+// Update writes the current version of a template if the stored revision
+// still matches, then records the version.
+func (s *TemplateStore) Update(ctx context.Context, tpl *model.PromptTemplate, revision uint64) (uint64, error) {
+	data, err := json.Marshal(tpl)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal template: %w", err)
+	}
+
+	rev, err := s.kv.Update(ctx, TemplateKey(tpl.TenantID, tpl.ID), data, revision)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return 0, ErrRevisionMismatch
+		}
+		return 0, fmt.Errorf("failed to update template: %w", err)
+	}
+
+	if err := s.putVersion(ctx, tpl, data); err != nil {
+		return 0, err
+	}
+
+	return rev, nil
+}
+
+// putVersion records a template version. Updates that leave the content
+// unchanged keep the version number and rewrite its record in place.
+func (s *TemplateStore) putVersion(ctx context.Context, tpl *model.PromptTemplate, data []byte) error {
+	if _, err := s.kv.Put(ctx, TemplateVersionKey(tpl.TenantID, tpl.ID, tpl.Version), data); err != nil {
+		return fmt.Errorf("failed to store template version: %w", err)
+	}
+	return nil
+}
+
+func (s *TemplateStore) get(ctx context.Context, key string) (*model.PromptTemplate, uint64, error) {
+	entry, err := s.kv.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return nil, 0, ErrNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	var tpl model.PromptTemplate
+	if err := json.Unmarshal(entry.Value(), &tpl); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal template: %w", err)
+	}
+
+	return &tpl, entry.Revision(), nil
+}
+
+// ToolStore persists tenant tool definitions in a JetStream KV bucket. Keys
+// are "{tenant}.{name}": the LLM calls tools by name, so names are unique
+// per tenant.
+type ToolStore struct {
+	kv jetstream.KeyValue
+}
+
+// NewToolStore creates or binds to the tool KV bucket.
+func NewToolStore(ctx context.Context, client *Client) (*ToolStore, error) {
+	kv, err := client.JetStream().CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      ToolBucket,
+		Description: "Tool definitions keyed by tenant and tool name",
+		History:     1,
+		Storage:     jetstream.FileStorage,
+		Replicas:    1,
+		Compression: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool bucket: %w", err)
+	}
+
+	return &ToolStore{kv: kv}, nil
+}
+
+// ToolKey returns the KV key for a tool.
+func ToolKey(tenantID, name string) string {
+	return fmt.Sprintf("%s.%s", tenantID, name)
+}
+
+// Create stores a new tool and returns its revision.
+func (s *ToolStore) Create(ctx context.Context, tool *model.Tool) (uint64, error) {
+	data, err := json.Marshal(tool)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal tool: %w", err)
+	}
+
+	rev, err := s.kv.Create(ctx, ToolKey(tool.TenantID, tool.Name), data)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return 0, ErrRevisionMismatch
+		}
+		return 0, fmt.Errorf("failed to create tool: %w", err)
+	}
+
+	return rev, nil
+}
+
+// Get retrieves a tool along with its current revision.
+func (s *ToolStore) Get(ctx context.Context, tenantID, name string) (*model.Tool, uint64, error) {
+	entry, err := s.kv.Get(ctx, ToolKey(tenantID, name))
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return nil, 0, ErrNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to get tool: %w", err)
+	}
+
+	var tool model.Tool
+	if err := json.Unmarshal(entry.Value(), &tool); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal tool: %w", err)
+	}
+
+	return &tool, entry.Revision(), nil
+}
+
+// List returns every tool stored for a tenant.
+func (s *ToolStore) List(ctx context.Context, tenantID string) ([]model.Tool, error) {
+	watcher, err := s.kv.Watch(ctx, ToolKey(tenantID, "*"), jetstream.IgnoreDeletes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch tools: %w", err)
+	}
+	defer watcher.Stop()
+
+	var tools []model.Tool
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case entry := <-watcher.Updates():
+			// A nil entry marks the end of the initial values.
+			if entry == nil {
+				return tools, nil
+			}
+
+			var tool model.Tool
+			if err := json.Unmarshal(entry.Value(), &tool); err != nil {
+				continue
+			}
+			tools = append(tools, tool)
+		}
+	}
+}
+
+// Update writes a tool if the stored revision still matches.
+func (s *ToolStore) Update(ctx context.Context, tool *model.Tool, revision uint64) (uint64, error) {
+	data, err := json.Marshal(tool)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal tool: %w", err)
+	}
+
+	rev, err := s.kv.Update(ctx, ToolKey(tool.TenantID, tool.Name), data, revision)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return 0, ErrRevisionMismatch
+		}
+		return 0, fmt.Errorf("failed to update tool: %w", err)
+	}
+
+	return rev, nil
+}
+
+// Delete removes a tool. Its name can be registered again afterwards.
+func (s *ToolStore) Delete(ctx context.Context, tenantID, name string) error {
+	if err := s.kv.Delete(ctx, ToolKey(tenantID, name)); err != nil {
+		return fmt.Errorf("failed to delete tool: %w", err)
+	}
+	return nil
+}
+
+// CancelStore marks generation jobs that were cancelled while still queued,
+// so the worker that picks one up drops it instead of generating. Keys are
+// "{tenant}.{job}"; marks expire with the jobs they refer to.
+type CancelStore struct {
+	kv jetstream.KeyValue
+}
+
+// NewCancelStore creates or binds to the cancelled jobs KV bucket.
+func NewCancelStore(ctx context.Context, client *Client) (*CancelStore, error) {
+	kv, err := client.JetStream().CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      CancelBucket,
+		Description: "Generation jobs cancelled before a worker ran them",
+		History:     1,
+		TTL:         JobMaxAge,
+		Storage:     jetstream.FileStorage,
+		Replicas:    1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cancel bucket: %w", err)
+	}
+
+	return &CancelStore{kv: kv}, nil
+}
+
+// JobKey returns the KV key for a tenant's generation job.
+func JobKey(tenantID, jobID string) string {
+	return fmt.Sprintf("%s.%s", tenantID, jobID)
+}
+
+// Mark records that a job was cancelled.
+func (s *CancelStore) Mark(ctx context.Context, tenantID, jobID string) error {
+	if _, err := s.kv.Put(ctx, JobKey(tenantID, jobID), []byte(time.Now().UTC().Format(time.RFC3339))); err != nil {
+		return fmt.Errorf("failed to mark job cancelled: %w", err)
+	}
+	return nil
+}
+
+// Marked reports whether a job was cancelled.
+func (s *CancelStore) Marked(ctx context.Context, tenantID, jobID string) (bool, error) {
+	if _, err := s.kv.Get(ctx, JobKey(tenantID, jobID)); err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get job cancel mark: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,108 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+)
+
+// LiveSubjectPrefix is the prefix for ephemeral, non-persisted subjects. It is
+// deliberately outside SubjectPrefix so the CONVERSATIONS stream never captures it.
+const LiveSubjectPrefix = "live"
+
+// TokenSubject returns the core NATS subject carrying in-flight tokens for a conversation.
+func TokenSubject(tenantID, conversationID string) string {
+	return fmt.Sprintf("%s.%s.%s.tokens", LiveSubjectPrefix, tenantID, conversationID)
+}
+
+// CancelSubject returns the core NATS subject used to cancel a conversation's generation.
+func CancelSubject(tenantID, conversationID string) string {
+	return fmt.Sprintf("%s.%s.%s.cancel", LiveSubjectPrefix, tenantID, conversationID)
+}
+
+// LiveRelay publishes and subscribes to ephemeral generation output over core NATS,
+// letting any replica serve a generation running on another.
+type LiveRelay struct {
+	client *Client
+}
+
+// NewLiveRelay creates a new live relay.
+func NewLiveRelay(client *Client) *LiveRelay {
+	return &LiveRelay{client: client}
+}
+
+// PublishToken publishes a token delta. Delivery is best effort.
+func (r *LiveRelay) PublishToken(tenantID, conversationID string, event *model.TokenEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	if err := r.client.Conn().Publish(TokenSubject(tenantID, conversationID), data); err != nil {
+		return fmt.Errorf("failed to publish token: %w", err)
+	}
+
+	return nil
+}
+
+// SubscribeTokens delivers token deltas for a conversation until ctx is cancelled.
+// Tokens are dropped rather than blocking the connection when the reader falls
+// behind; clients recover them by index.
+func (r *LiveRelay) SubscribeTokens(ctx context.Context, tenantID, conversationID string) (<-chan model.TokenEvent, error) {
+	tokens := make(chan model.TokenEvent, 256)
+
+	sub, err := r.client.Conn().Subscribe(TokenSubject(tenantID, conversationID), func(msg *nats.Msg) {
+		var event model.TokenEvent
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			return
+		}
+
+		select {
+		case tokens <- event:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to tokens: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		sub.Unsubscribe()
+	}()
+
+	return tokens, nil
+}
+
+// RequestCancel asks whichever replica is generating for a conversation to stop.
+// It reports false if no generation is running anywhere.
+func (r *LiveRelay) RequestCancel(ctx context.Context, tenantID, conversationID string) (bool, error) {
+	_, err := r.client.Conn().RequestWithContext(ctx, CancelSubject(tenantID, conversationID), nil)
+	if err != nil {
+		if errors.Is(err, nats.ErrNoResponders) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to request cancel: %w", err)
+	}
+
+	return true, nil
+}
+
+// OnCancel calls fn when a cancel is requested for a conversation, acknowledging
+// the requester. The returned function removes the subscription.
+func (r *LiveRelay) OnCancel(tenantID, conversationID string, fn func()) (func(), error) {
+	sub, err := r.client.Conn().Subscribe(CancelSubject(tenantID, conversationID), func(msg *nats.Msg) {
+		fn()
+		msg.Respond(nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to cancel: %w", err)
+	}
+
+	return func() { sub.Unsubscribe() }, nil
+}
@@ -0,0 +1,193 @@
+package nats
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+)
+
+// AttachmentBucketPrefix prefixes the per-tenant object store buckets
+// holding attachments.
+const AttachmentBucketPrefix = "ATTACHMENTS_"
+
+// AttachmentStore persists attachment content in JetStream object stores,
+// one bucket per tenant so each tenant's usage is bounded by its bucket size.
+// Attachment metadata is kept on the object itself.
+type AttachmentStore struct {
+	client   *Client
+	maxBytes int64
+
+	mu      sync.Mutex
+	buckets map[string]jetstream.ObjectStore
+}
+
+// NewAttachmentStore creates an attachment store. Tenant buckets are created
+// on first use and capped at maxBytes.
+func NewAttachmentStore(client *Client, maxBytes int64) *AttachmentStore {
+	return &AttachmentStore{
+		client:   client,
+		maxBytes: maxBytes,
+		buckets:  make(map[string]jetstream.ObjectStore),
+	}
+}
+
+// AttachmentBucket returns the object store bucket holding a tenant's
+// attachments. Characters bucket names cannot hold are hex escaped.
+func AttachmentBucket(tenantID string) string {
+	var b strings.Builder
+	b.WriteString(AttachmentBucketPrefix)
+	for i := 0; i < len(tenantID); i++ {
+		c := tenantID[i]
+		if c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "_%02x", c)
+	}
+	return b.String()
+}
+
+// Put streams an attachment's content into the tenant's bucket and returns
+// the attachment with its size and SHA-256 digest filled in. A read error
+// aborts the upload and discards what was written.
+func (s *AttachmentStore) Put(ctx context.Context, att *model.Attachment, content io.Reader) (*model.Attachment, error) {
+	bucket, err := s.bucket(ctx, att.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := bucket.Put(ctx, jetstream.ObjectMeta{
+		Name: att.ID,
+		Metadata: map[string]string{
+			"conversation_id": att.ConversationID,
+			"filename":        att.Filename,
+			"content_type":    att.ContentType,
+			"created_at":      att.CreatedAt.Format(time.RFC3339Nano),
+		},
+	}, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+
+	return attachmentFromInfo(att.TenantID, info)
+}
+
+// Get retrieves an attachment's metadata.
+func (s *AttachmentStore) Get(ctx context.Context, tenantID, id string) (*model.Attachment, error) {
+	bucket, err := s.bucket(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := bucket.GetInfo(ctx, id)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrObjectNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+
+	return attachmentFromInfo(tenantID, info)
+}
+
+// Open returns an attachment's metadata and a reader over its content. The
+// caller must close the reader.
+func (s *AttachmentStore) Open(ctx context.Context, tenantID, id string) (*model.Attachment, io.ReadCloser, error) {
+	bucket, err := s.bucket(ctx, tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := bucket.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrObjectNotFound) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+
+	info, err := result.Info()
+	if err != nil {
+		result.Close()
+		return nil, nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+
+	att, err := attachmentFromInfo(tenantID, info)
+	if err != nil {
+		result.Close()
+		return nil, nil, err
+	}
+
+	return att, result, nil
+}
+
+// Usage returns the bytes a tenant's bucket holds.
+func (s *AttachmentStore) Usage(ctx context.Context, tenantID string) (uint64, error) {
+	bucket, err := s.bucket(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	status, err := bucket.Status(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get attachment bucket status: %w", err)
+	}
+
+	return status.Size(), nil
+}
+
+// bucket returns a tenant's bucket, creating it if needed.
+func (s *AttachmentStore) bucket(ctx context.Context, tenantID string) (jetstream.ObjectStore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if bucket, ok := s.buckets[tenantID]; ok {
+		return bucket, nil
+	}
+
+	bucket, err := s.client.JetStream().CreateOrUpdateObjectStore(ctx, jetstream.ObjectStoreConfig{
+		Bucket:      AttachmentBucket(tenantID),
+		Description: "Conversation attachments for tenant " + tenantID,
+		MaxBytes:    s.maxBytes,
+		Storage:     jetstream.FileStorage,
+		Replicas:    1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment bucket: %w", err)
+	}
+
+	s.buckets[tenantID] = bucket
+	return bucket, nil
+}
+
+// attachmentFromInfo rebuilds an attachment from its object's metadata.
+func attachmentFromInfo(tenantID string, info *jetstream.ObjectInfo) (*model.Attachment, error) {
+	// Object digests are "SHA-256=" followed by the base64url encoded sum
+	sum, err := base64.URLEncoding.DecodeString(strings.TrimPrefix(info.Digest, "SHA-256="))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode attachment digest: %w", err)
+	}
+
+	createdAt, _ := time.Parse(time.RFC3339Nano, info.Metadata["created_at"])
+
+	return &model.Attachment{
+		ID:             info.Name,
+		TenantID:       tenantID,
+		ConversationID: info.Metadata["conversation_id"],
+		Filename:       info.Metadata["filename"],
+		ContentType:    info.Metadata["content_type"],
+		Size:           int64(info.Size),
+		SHA256:         hex.EncodeToString(sum),
+		CreatedAt:      createdAt,
+	}, nil
+}
@@ -3,7 +3,9 @@ package nats
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/nats-io/nats.go/jetstream"
@@ -70,6 +72,59 @@ func EventSubject(tenantID, conversationID string, eventType model.EventType) st
 	return fmt.Sprintf("%s.%s.%s.event.%s", SubjectPrefix, tenantID, conversationID, eventType)
 }
 
+// LifecycleSubject returns the subject for a conversation lifecycle event.
+func LifecycleSubject(tenantID, conversationID string, action model.LifecycleAction) string {
+	return fmt.Sprintf("%s.%s.%s.lifecycle.%s", SubjectPrefix, tenantID, conversationID, action)
+}
+
+// SummarySubject returns the subject for a conversation's rolling summaries.
+func SummarySubject(tenantID, conversationID string) string {
+	return fmt.Sprintf("%s.%s.%s.summary", SubjectPrefix, tenantID, conversationID)
+}
+
+// AllMessagesFilter returns the filter subject for messages across every conversation.
+func AllMessagesFilter() string {
+	return fmt.Sprintf("%s.*.*.msg.>", SubjectPrefix)
+}
+
+// AllLifecycleFilter returns the filter subject for lifecycle events across every conversation.
+func AllLifecycleFilter() string {
+	return fmt.Sprintf("%s.*.*.lifecycle.>", SubjectPrefix)
+}
+
+// SubjectInfo is the decoded form of a conversation subject.
+type SubjectInfo struct {
+	TenantID       string
+	ConversationID string
+	Kind           string // msg, event, lifecycle, ...
+	Name           string // role, event type or lifecycle action
+}
+
+// ParseSubject splits a conv.{tenant}.{conv}.{kind}.{name} subject into its parts.
+func ParseSubject(subject string) (SubjectInfo, bool) {
+	parts := strings.Split(subject, ".")
+	if len(parts) < 5 || parts[0] != SubjectPrefix {
+		return SubjectInfo{}, false
+	}
+
+	return SubjectInfo{
+		TenantID:       parts[1],
+		ConversationID: parts[2],
+		Kind:           parts[3],
+		Name:           strings.Join(parts[4:], "."),
+	}, true
+}
+
+// MessageFilter returns the filter subject for persisted messages in a conversation.
+func MessageFilter(tenantID, conversationID string) string {
+	return fmt.Sprintf("%s.%s.%s.msg.>", SubjectPrefix, tenantID, conversationID)
+}
+
+// EventFilter returns the filter subject for events in a conversation.
+func EventFilter(tenantID, conversationID string) string {
+	return fmt.Sprintf("%s.%s.%s.event.>", SubjectPrefix, tenantID, conversationID)
+}
+
 // ConversationFilter returns the filter subject for all messages in a conversation.
 func ConversationFilter(tenantID, conversationID string) string {
 	return fmt.Sprintf("%s.%s.%s.>", SubjectPrefix, tenantID, conversationID)
@@ -84,7 +139,9 @@ func (m *StreamManager) PublishMessage(ctx context.Context, msg *model.Message)
 		return 0, fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	ack, err := m.client.JetStream().Publish(ctx, subject, data)
+	// The message ID doubles as the JetStream message ID so a redelivered
+	// generation job cannot persist the same message twice
+	ack, err := m.client.JetStream().Publish(ctx, subject, data, jetstream.WithMsgID(msg.ID))
 	if err != nil {
 		return 0, fmt.Errorf("failed to publish message: %w", err)
 	}
@@ -109,12 +166,265 @@ func (m *StreamManager) PublishEvent(ctx context.Context, event *model.Conversat
 	return ack.Sequence, nil
 }
 
+// PublishLifecycle publishes a conversation lifecycle event to JetStream.
+func (m *StreamManager) PublishLifecycle(ctx context.Context, event *model.ConversationLifecycleEvent) (uint64, error) {
+	subject := LifecycleSubject(event.Conversation.TenantID, event.Conversation.ID, event.Action)
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal lifecycle event: %w", err)
+	}
+
+	ack, err := m.client.JetStream().Publish(ctx, subject, data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish lifecycle event: %w", err)
+	}
+
+	return ack.Sequence, nil
+}
+
+// PublishSummary publishes a conversation summary to JetStream.
+func (m *StreamManager) PublishSummary(ctx context.Context, summary *model.Summary) (uint64, error) {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal summary: %w", err)
+	}
+
+	ack, err := m.client.JetStream().Publish(ctx, SummarySubject(summary.TenantID, summary.ConversationID), data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish summary: %w", err)
+	}
+
+	return ack.Sequence, nil
+}
+
+// LatestSummary returns the newest summary of a conversation, or ErrNotFound
+// if it has never been summarized.
+func (m *StreamManager) LatestSummary(ctx context.Context, tenantID, conversationID string) (*model.Summary, error) {
+	stream, err := m.client.JetStream().Stream(ctx, StreamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream: %w", err)
+	}
+
+	msg, err := stream.GetLastMsgForSubject(ctx, SummarySubject(tenantID, conversationID))
+	if err != nil {
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get summary: %w", err)
+	}
+
+	var summary model.Summary
+	if err := json.Unmarshal(msg.Data, &summary); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal summary: %w", err)
+	}
+	summary.Sequence = msg.Sequence
+
+	return &summary, nil
+}
+
+// ReplayHandler is called for each stream message during a replay.
+type ReplayHandler func(subject string, sequence uint64, data []byte) error
+
+// replayBatchSize is the number of messages fetched per round trip during replay.
+const replayBatchSize = 256
+
+// Replay delivers every message matching filters after afterSequence to handler,
+// stopping once the messages pending at the start of the replay are consumed.
+// It returns the stream sequence of the last message handled.
+func (m *StreamManager) Replay(ctx context.Context, filters []string, afterSequence uint64, handler ReplayHandler) (uint64, error) {
+	js := m.client.JetStream()
+
+	consumerConfig := jetstream.ConsumerConfig{
+		FilterSubjects:    filters,
+		AckPolicy:         jetstream.AckNonePolicy,
+		DeliverPolicy:     jetstream.DeliverAllPolicy,
+		InactiveThreshold: time.Minute,
+	}
+
+	if afterSequence > 0 {
+		consumerConfig.DeliverPolicy = jetstream.DeliverByStartSequencePolicy
+		consumerConfig.OptStartSeq = afterSequence + 1
+	}
+
+	consumer, err := js.CreateConsumer(ctx, StreamName, consumerConfig)
+	if err != nil {
+		return afterSequence, fmt.Errorf("failed to create consumer: %w", err)
+	}
+
+	lastSequence := afterSequence
+	remaining := consumer.CachedInfo().NumPending
+
+	for remaining > 0 {
+		if err := ctx.Err(); err != nil {
+			return lastSequence, err
+		}
+
+		size := replayBatchSize
+		if remaining < uint64(size) {
+			size = int(remaining)
+		}
+
+		batch, err := consumer.Fetch(size, jetstream.FetchMaxWait(2*time.Second))
+		if err != nil {
+			return lastSequence, fmt.Errorf("failed to fetch messages: %w", err)
+		}
+
+		received := 0
+		for msg := range batch.Messages() {
+			meta, err := msg.Metadata()
+			if err != nil {
+				continue
+			}
+
+			if err := handler(msg.Subject(), meta.Sequence.Stream, msg.Data()); err != nil {
+				return lastSequence, err
+			}
+
+			lastSequence = meta.Sequence.Stream
+			remaining = meta.NumPending
+			received++
+		}
+
+		if batch.Error() != nil && batch.Error() != context.DeadlineExceeded {
+			return lastSequence, fmt.Errorf("batch error: %w", batch.Error())
+		}
+
+		if received == 0 {
+			break
+		}
+	}
+
+	return lastSequence, nil
+}
+
+// ScanHandler is called for each stream message during a backward scan. It
+// returns false to stop the scan.
+type ScanHandler func(subject string, sequence uint64, data []byte) (bool, error)
+
+// scanWindow is the initial span of stream sequences read per step of a
+// backward scan. It doubles each step, since the stream interleaves every
+// conversation and a quiet one is spread thinly across it.
+const scanWindow = 256
+
+// errWindowEnd stops a replay at the upper bound of a scan window.
+var errWindowEnd = errors.New("end of scan window")
+
+// ScanBackward delivers the messages matching filter to handler newest first,
+// starting at throughSequence (or the stream tail if zero) and moving towards
+// the start of the stream until handler returns false.
+func (m *StreamManager) ScanBackward(ctx context.Context, filter string, throughSequence uint64, handler ScanHandler) error {
+	upper := throughSequence
+	if upper == 0 {
+		stream, err := m.client.JetStream().Stream(ctx, StreamName)
+		if err != nil {
+			return fmt.Errorf("failed to get stream: %w", err)
+		}
+		upper = stream.CachedInfo().State.LastSeq
+	}
+
+	window := uint64(scanWindow)
+	for upper > 0 {
+		lower := uint64(1)
+		if upper > window {
+			lower = upper - window + 1
+		}
+
+		// JetStream only reads forward, so fetch the window and walk it in reverse
+		var page []StreamEntry
+		_, err := m.Replay(ctx, []string{filter}, lower-1, func(subject string, sequence uint64, data []byte) error {
+			if sequence > upper {
+				return errWindowEnd
+			}
+			page = append(page, StreamEntry{Subject: subject, Sequence: sequence, Data: data})
+			return nil
+		})
+		if err != nil && !errors.Is(err, errWindowEnd) {
+			return err
+		}
+
+		for i := len(page) - 1; i >= 0; i-- {
+			more, err := handler(page[i].Subject, page[i].Sequence, page[i].Data)
+			if err != nil {
+				return err
+			}
+			if !more {
+				return nil
+			}
+		}
+
+		upper = lower - 1
+		window *= 2
+	}
+
+	return nil
+}
+
+// StreamEntry is a raw conversation stream message delivered to a follower.
+type StreamEntry struct {
+	Subject  string
+	Sequence uint64
+	Data     []byte
+}
+
+// Follow delivers the messages and events of a conversation published after
+// afterSequence until ctx is cancelled. Starting from an explicit sequence lets
+// callers hand over from a replay without gaps or duplicates. The returned
+// channel is closed when following stops.
+func (m *StreamManager) Follow(ctx context.Context, tenantID, conversationID string, afterSequence uint64) (<-chan StreamEntry, error) {
+	consumer, err := m.client.JetStream().OrderedConsumer(ctx, StreamName, jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{
+			MessageFilter(tenantID, conversationID),
+			EventFilter(tenantID, conversationID),
+		},
+		DeliverPolicy: jetstream.DeliverByStartSequencePolicy,
+		OptStartSeq:   afterSequence + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ordered consumer: %w", err)
+	}
+
+	iter, err := consumer.Messages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start consumer: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		iter.Stop()
+	}()
+
+	entries := make(chan StreamEntry, 64)
+	go func() {
+		defer close(entries)
+		for {
+			msg, err := iter.Next()
+			if err != nil {
+				return
+			}
+
+			meta, err := msg.Metadata()
+			if err != nil {
+				continue
+			}
+
+			select {
+			case entries <- StreamEntry{Subject: msg.Subject(), Sequence: meta.Sequence.Stream, Data: msg.Data()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return entries, nil
+}
+
 // GetMessages retrieves messages from a conversation starting after a sequence.
 func (m *StreamManager) GetMessages(ctx context.Context, tenantID, conversationID string, afterSequence uint64, limit int) ([]model.Message, uint64, bool, error) {
 	js := m.client.JetStream()
 
 	// Create ephemeral consumer
-	filterSubject := fmt.Sprintf("%s.%s.%s.msg.>", SubjectPrefix, tenantID, conversationID)
+	filterSubject := MessageFilter(tenantID, conversationID)
 
 	consumerConfig := jetstream.ConsumerConfig{
 		FilterSubject: filterSubject,
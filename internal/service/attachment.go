@@ -0,0 +1,332 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+	natsclient "github.com/capitalize-ai/conversational-platform/internal/nats"
+	"github.com/capitalize-ai/conversational-platform/pkg/logger"
+)
+
+var (
+	// ErrAttachmentNotFound is returned when an attachment does not exist in
+	// the conversation.
+	ErrAttachmentNotFound = errors.New("attachment not found")
+
+	// ErrAttachmentTooLarge is returned when an upload exceeds the size limit
+	// for its type.
+	ErrAttachmentTooLarge = errors.New("attachment exceeds maximum size")
+
+	// ErrAttachmentQuota is returned when an upload would exceed the tenant's
+	// attachment quota.
+	ErrAttachmentQuota = errors.New("attachment quota exceeded")
+
+	// ErrUnsupportedAttachment is returned when an upload's sniffed content
+	// type is not one the LLM layer can inline.
+	ErrUnsupportedAttachment = errors.New("unsupported attachment type")
+)
+
+const (
+	// sniffLen is the number of leading bytes content type sniffing reads.
+	sniffLen = 512
+
+	// maxInlineImageBytes is the largest image providers accept inline.
+	maxInlineImageBytes = 5 * 1024 * 1024
+
+	// maxExtractedTextBytes caps the text inlined for one attachment.
+	maxExtractedTextBytes = 100000
+)
+
+// attachmentTypes are the content types attachments may have.
+var attachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+// AttachmentRepository persists attachment content and metadata.
+type AttachmentRepository interface {
+	// Put streams an attachment's content into the store and returns it with
+	// its size and digest set.
+	Put(ctx context.Context, att *model.Attachment, content io.Reader) (*model.Attachment, error)
+
+	// Get returns an attachment's metadata.
+	Get(ctx context.Context, tenantID, id string) (*model.Attachment, error)
+
+	// Open returns an attachment's metadata and content.
+	Open(ctx context.Context, tenantID, id string) (*model.Attachment, io.ReadCloser, error)
+
+	// Usage returns the bytes stored for a tenant.
+	Usage(ctx context.Context, tenantID string) (uint64, error)
+}
+
+// AttachmentConfig configures attachment limits.
+type AttachmentConfig struct {
+	// MaxBytes caps each upload. Images are further capped at what providers
+	// accept inline.
+	MaxBytes int64
+
+	// TenantQuota caps the bytes stored for each tenant.
+	TenantQuota int64
+}
+
+// AttachmentService handles files uploaded to conversations and inlines them
+// into LLM requests.
+type AttachmentService struct {
+	store  AttachmentRepository
+	config AttachmentConfig
+	logger *logger.Logger
+}
+
+// NewAttachmentService creates a new attachment service.
+func NewAttachmentService(store AttachmentRepository, config AttachmentConfig, log *logger.Logger) *AttachmentService {
+	return &AttachmentService{
+		store:  store,
+		config: config,
+		logger: log,
+	}
+}
+
+// Upload streams a file into the tenant's attachment store. Its content type
+// is sniffed from its leading bytes rather than trusted from the client.
+func (s *AttachmentService) Upload(ctx context.Context, tenantID, conversationID, filename string, content io.Reader) (*model.Attachment, error) {
+	used, err := s.store.Usage(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment usage: %w", err)
+	}
+	remaining := s.config.TenantQuota - int64(used)
+	if remaining <= 0 {
+		return nil, ErrAttachmentQuota
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	head = head[:n]
+
+	contentType, ok := sniffContentType(head)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAttachment, contentType)
+	}
+
+	limit := s.config.MaxBytes
+	if isImage(contentType) {
+		limit = min(limit, maxInlineImageBytes)
+	}
+	limitErr := ErrAttachmentTooLarge
+	if remaining < limit {
+		limit, limitErr = remaining, ErrAttachmentQuota
+	}
+
+	att := &model.Attachment{
+		ID:             uuid.Must(uuid.NewV7()).String(),
+		TenantID:       tenantID,
+		ConversationID: conversationID,
+		Filename:       filename,
+		ContentType:    contentType,
+		CreatedAt:      time.Now(),
+	}
+
+	stored, err := s.store.Put(ctx, att, &cappedReader{
+		r:         io.MultiReader(bytes.NewReader(head), content),
+		remaining: limit,
+		err:       limitErr,
+	})
+	if err != nil {
+		if errors.Is(err, ErrAttachmentTooLarge) || errors.Is(err, ErrAttachmentQuota) {
+			return nil, limitErr
+		}
+		return nil, err
+	}
+
+	s.logger.Info("attachment uploaded",
+		zap.String("attachment_id", stored.ID),
+		zap.String("conversation_id", conversationID),
+		zap.String("content_type", contentType),
+		zap.Int64("size", stored.Size),
+	)
+
+	return stored, nil
+}
+
+// Get retrieves an attachment of a conversation.
+func (s *AttachmentService) Get(ctx context.Context, tenantID, conversationID, id string) (*model.Attachment, error) {
+	att, err := s.store.Get(ctx, tenantID, id)
+	if err != nil {
+		if errors.Is(err, natsclient.ErrNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+
+	if att.ConversationID != conversationID {
+		return nil, ErrAttachmentNotFound
+	}
+
+	return att, nil
+}
+
+// Open retrieves an attachment of a conversation along with its content. The
+// caller must close the reader.
+func (s *AttachmentService) Open(ctx context.Context, tenantID, conversationID, id string) (*model.Attachment, io.ReadCloser, error) {
+	att, content, err := s.store.Open(ctx, tenantID, id)
+	if err != nil {
+		if errors.Is(err, natsclient.ErrNotFound) {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		return nil, nil, err
+	}
+
+	if att.ConversationID != conversationID {
+		content.Close()
+		return nil, nil, ErrAttachmentNotFound
+	}
+
+	return att, content, nil
+}
+
+// Resolve checks that the attachments a message references belong to its
+// conversation and fills in their names and media types.
+func (s *AttachmentService) Resolve(ctx context.Context, tenantID, conversationID string, blocks []model.ContentBlock) error {
+	for i := range blocks {
+		if blocks[i].Type != model.ContentBlockAttachment {
+			continue
+		}
+
+		att, err := s.Get(ctx, tenantID, conversationID, blocks[i].AttachmentID)
+		if err != nil {
+			return err
+		}
+
+		blocks[i].Name = att.Filename
+		blocks[i].MediaType = att.ContentType
+	}
+	return nil
+}
+
+// Inline replaces the attachment blocks of a message with their content:
+// images become image blocks and documents the text extracted from them.
+// Attachments that can no longer be read are replaced by a note saying so.
+func (s *AttachmentService) Inline(ctx context.Context, msg *model.Message) []model.ContentBlock {
+	blocks := make([]model.ContentBlock, 0, len(msg.ContentBlocks))
+	for _, block := range msg.ContentBlocks {
+		if block.Type != model.ContentBlockAttachment {
+			blocks = append(blocks, block)
+			continue
+		}
+
+		inlined, err := s.inline(ctx, msg.TenantID, msg.ConversationID, block.AttachmentID)
+		if err != nil {
+			s.logger.Warn("failed to inline attachment",
+				zap.String("attachment_id", block.AttachmentID),
+				zap.String("conversation_id", msg.ConversationID),
+				zap.Error(err),
+			)
+			inlined = model.ContentBlock{
+				Type: model.ContentBlockText,
+				Text: fmt.Sprintf("[Attachment %q is no longer available.]", block.Name),
+			}
+		}
+		blocks = append(blocks, inlined)
+	}
+	return blocks
+}
+
+// inline reads an attachment and returns the block standing in for it.
+func (s *AttachmentService) inline(ctx context.Context, tenantID, conversationID, id string) (model.ContentBlock, error) {
+	att, content, err := s.Open(ctx, tenantID, conversationID, id)
+	if err != nil {
+		return model.ContentBlock{}, err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return model.ContentBlock{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	if isImage(att.ContentType) {
+		return model.ContentBlock{
+			Type: model.ContentBlockImage,
+			Source: &model.ImageSource{
+				Type:      model.ImageSourceBase64,
+				MediaType: att.ContentType,
+				Data:      base64.StdEncoding.EncodeToString(data),
+			},
+		}, nil
+	}
+
+	var text string
+	if att.ContentType == "application/pdf" {
+		text = extractPDFText(data)
+	} else {
+		text = string(bytes.ToValidUTF8(data, []byte("\uFFFD")))
+	}
+	if len(text) > maxExtractedTextBytes {
+		text = text[:maxExtractedTextBytes]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+		text += "\n[truncated]"
+	}
+
+	return model.ContentBlock{
+		Type: model.ContentBlockText,
+		Text: fmt.Sprintf("Attachment %q:\n%s", att.Filename, text),
+	}, nil
+}
+
+// sniffContentType detects the content type of a file from its leading
+// bytes, dropping parameters, and reports whether it may be attached.
+func sniffContentType(head []byte) (string, bool) {
+	detected := http.DetectContentType(head)
+	mediaType, params, err := mime.ParseMediaType(detected)
+	if err != nil {
+		return detected, false
+	}
+	if mediaType == "text/plain" && params["charset"] != "utf-8" {
+		return detected, false
+	}
+	return mediaType, attachmentTypes[mediaType]
+}
+
+// isImage reports whether a content type is an image.
+func isImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+// cappedReader fails with err once more than remaining bytes are read, which
+// aborts the object store upload reading from it.
+type cappedReader struct {
+	r         io.Reader
+	remaining int64
+	err       error
+}
+
+// Read implements io.Reader.
+func (c *cappedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	if c.remaining < 0 {
+		return 0, c.err
+	}
+	return n, err
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/capitalize-ai/conversational-platform/internal/llm"
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+	natsclient "github.com/capitalize-ai/conversational-platform/internal/nats"
+)
+
+// ContextBuilder assembles the history sent to the LLM for a generation. It
+// reads backward from the newest message so long conversations keep their
+// latest turns, and stops once the token budget is spent or it reaches the
+// history covered by the latest summary.
+type ContextBuilder struct {
+	streamManager *natsclient.StreamManager
+	summarizer    *Summarizer
+	attachments   *AttachmentService
+}
+
+// NewContextBuilder creates a new context builder.
+func NewContextBuilder(streamManager *natsclient.StreamManager, summarizer *Summarizer, attachments *AttachmentService) *ContextBuilder {
+	return &ContextBuilder{
+		streamManager: streamManager,
+		summarizer:    summarizer,
+		attachments:   attachments,
+	}
+}
+
+// Build returns the conversation's system messages, its latest summary and
+// the newest messages up to and including throughSequence that fit in budget
+// tokens of modelName, oldest first. The message at throughSequence is always
+// included. Attachments are inlined, so their content counts against the budget.
+func (b *ContextBuilder) Build(ctx context.Context, tenantID, conversationID string, throughSequence uint64, modelName string, budget int) ([]llm.ChatMessage, error) {
+	// System prompts apply to the whole conversation however old they are
+	var system []llm.ChatMessage
+	systemFilter := natsclient.MessageSubject(tenantID, conversationID, model.RoleSystem)
+	_, err := b.streamManager.Replay(ctx, []string{systemFilter}, 0, func(_ string, sequence uint64, data []byte) error {
+		if throughSequence > 0 && sequence > throughSequence {
+			return nil
+		}
+
+		var msg model.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil
+		}
+
+		chatMsg := llm.ChatMessage{Role: string(msg.Role), Content: msg.Content}
+		system = append(system, chatMsg)
+		budget -= llm.EstimateTokens(modelName, chatMsg)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system messages: %w", err)
+	}
+
+	// The summary stands in for everything it covers
+	summary, err := b.summarizer.Latest(ctx, tenantID, conversationID, throughSequence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load summary: %w", err)
+	}
+
+	var summarized uint64
+	if summary != nil {
+		chatMsg := llm.ChatMessage{
+			Role:    string(model.RoleSystem),
+			Content: "Summary of the earlier conversation:\n" + summary.Content,
+		}
+		system = append(system, chatMsg)
+		budget -= llm.EstimateTokens(modelName, chatMsg)
+		summarized = summary.ThroughSequence
+	}
+
+	// Collected newest first, reversed below
+	var tail []llm.ChatMessage
+	err = b.streamManager.ScanBackward(ctx, natsclient.MessageFilter(tenantID, conversationID), throughSequence, func(_ string, sequence uint64, data []byte) (bool, error) {
+		if sequence <= summarized && len(tail) > 0 {
+			return false, nil
+		}
+
+		var msg model.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return true, nil
+		}
+		if msg.Role == model.RoleSystem {
+			return true, nil
+		}
+
+		if hasAttachments(&msg) {
+			msg.ContentBlocks = b.attachments.Inline(ctx, &msg)
+		}
+
+		chatMsg := chatMessage(&msg)
+		cost := llm.EstimateTokens(modelName, chatMsg)
+		if cost > budget && len(tail) > 0 {
+			return false, nil
+		}
+
+		tail = append(tail, chatMsg)
+		budget -= cost
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message history: %w", err)
+	}
+
+	// Providers expect the history to open with a user turn
+	for len(tail) > 1 && tail[len(tail)-1].Role != string(model.RoleUser) {
+		tail = tail[:len(tail)-1]
+	}
+
+	slices.Reverse(tail)
+
+	messages := make([]llm.ChatMessage, 0, len(system)+len(tail))
+	messages = append(messages, system...)
+	messages = append(messages, pairToolCalls(tail)...)
+
+	return messages, nil
+}
+
+// chatMessage converts a persisted message to the LLM's format.
+func chatMessage(msg *model.Message) llm.ChatMessage {
+	chatMsg := llm.ChatMessage{
+		Role:       string(msg.Role),
+		Content:    msg.Content,
+		ToolCallID: msg.ToolCallID,
+		IsError:    msg.IsError,
+	}
+	for _, call := range msg.ToolCalls {
+		chatMsg.ToolCalls = append(chatMsg.ToolCalls, llm.ToolCall{
+			ID:        call.ID,
+			Name:      call.Name,
+			Arguments: call.Arguments,
+		})
+	}
+	if len(msg.ContentBlocks) > 0 {
+		chatMsg.Blocks = chatBlocks(msg.ContentBlocks)
+	}
+	return chatMsg
+}
+
+// chatBlocks converts persisted content blocks to the LLM's format.
+// Citations are output only and are not sent back; thinking is, as providers
+// require it alongside the tool calls it led to.
+func chatBlocks(blocks []model.ContentBlock) []llm.ContentBlock {
+	converted := make([]llm.ContentBlock, 0, len(blocks))
+	for _, block := range blocks {
+		switch block.Type {
+		case model.ContentBlockText:
+			converted = append(converted, llm.ContentBlock{Type: llm.BlockText, Text: block.Text})
+		case model.ContentBlockImage:
+			converted = append(converted, llm.ContentBlock{
+				Type:  llm.BlockImage,
+				Image: &llm.Image{MediaType: block.Source.MediaType, Data: block.Source.Data},
+			})
+		case model.ContentBlockToolUse:
+			converted = append(converted, llm.ContentBlock{
+				Type:     llm.BlockToolUse,
+				ToolCall: &llm.ToolCall{ID: block.ID, Name: block.Name, Arguments: block.Input},
+			})
+		case model.ContentBlockToolResult:
+			converted = append(converted, llm.ContentBlock{
+				Type:       llm.BlockToolResult,
+				ToolCallID: block.ToolUseID,
+				Content:    chatBlocks(block.Content),
+				IsError:    block.IsError,
+			})
+		case model.ContentBlockThinking:
+			converted = append(converted, llm.ContentBlock{
+				Type:      llm.BlockThinking,
+				Thinking:  block.Thinking,
+				Signature: block.Signature,
+			})
+		case model.ContentBlockRedactedThinking:
+			converted = append(converted, llm.ContentBlock{Type: llm.BlockRedactedThinking, Data: block.Data})
+		case model.ContentBlockAttachment:
+			// Stands in for attachments that were not inlined
+			converted = append(converted, llm.ContentBlock{
+				Type: llm.BlockText,
+				Text: fmt.Sprintf("[Attachment %q]", block.Name),
+			})
+		}
+	}
+	return converted
+}
+
+// hasAttachments reports whether a message references attachments.
+func hasAttachments(msg *model.Message) bool {
+	for _, block := range msg.ContentBlocks {
+		if block.Type == model.ContentBlockAttachment {
+			return true
+		}
+	}
+	return false
+}
+
+// pairToolCalls drops tool calls without a result and results without a
+// call, which providers reject. They appear when a generation dies between
+// persisting a call and its result.
+func pairToolCalls(messages []llm.ChatMessage) []llm.ChatMessage {
+	paired := make([]llm.ChatMessage, 0, len(messages))
+	for i := 0; i < len(messages); i++ {
+		msg := messages[i]
+		if msg.Role == string(model.RoleTool) {
+			// Results are consumed with the call they answer
+			continue
+		}
+		if len(msg.ToolCalls) == 0 {
+			paired = append(paired, msg)
+			continue
+		}
+
+		results := make(map[string]llm.ChatMessage)
+		for i+1 < len(messages) && messages[i+1].Role == string(model.RoleTool) {
+			i++
+			results[messages[i].ToolCallID] = messages[i]
+		}
+
+		var answered []llm.ToolCall
+		var answers []llm.ChatMessage
+		for _, call := range msg.ToolCalls {
+			if result, ok := results[call.ID]; ok {
+				answered = append(answered, call)
+				answers = append(answers, result)
+			}
+		}
+
+		msg.ToolCalls = answered
+		if len(msg.Blocks) > 0 {
+			msg.Blocks = slices.DeleteFunc(slices.Clone(msg.Blocks), func(block llm.ContentBlock) bool {
+				if block.Type != llm.BlockToolUse {
+					return false
+				}
+				_, ok := results[block.ToolCall.ID]
+				return !ok
+			})
+		}
+		if msg.Content == "" && len(answered) == 0 {
+			continue
+		}
+		paired = append(paired, msg)
+		paired = append(paired, answers...)
+	}
+	return paired
+}
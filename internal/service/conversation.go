@@ -3,38 +3,87 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sync"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 
+	"github.com/capitalize-ai/conversational-platform/internal/llm"
 	"github.com/capitalize-ai/conversational-platform/internal/model"
 	natsclient "github.com/capitalize-ai/conversational-platform/internal/nats"
 	"github.com/capitalize-ai/conversational-platform/pkg/logger"
 )
 
+var (
+	// ErrConversationNotFound is returned when a conversation does not exist for the tenant.
+	ErrConversationNotFound = errors.New("conversation not found")
+
+	// ErrConversationConflict is returned when concurrent writers keep racing on a conversation.
+	ErrConversationConflict = errors.New("conversation was modified concurrently")
+
+	// ErrInvalidProfile is returned when a generation profile exceeds the provider's limits.
+	ErrInvalidProfile = errors.New("invalid generation profile")
+)
+
+// maxUpdateAttempts bounds the optimistic concurrency retry loop.
+const maxUpdateAttempts = 5
+
+// ConversationRepository persists conversations with revision-based optimistic concurrency.
+type ConversationRepository interface {
+	// Create stores a new conversation and returns its revision.
+	Create(ctx context.Context, conv *model.Conversation) (uint64, error)
+
+	// Get returns a conversation and its current revision.
+	Get(ctx context.Context, tenantID, conversationID string) (*model.Conversation, uint64, error)
+
+	// List returns all conversations stored for a tenant.
+	List(ctx context.Context, tenantID string) ([]model.Conversation, error)
+
+	// Update writes a conversation if its stored revision still matches.
+	Update(ctx context.Context, conv *model.Conversation, revision uint64) (uint64, error)
+}
+
 // ConversationService handles conversation operations.
 type ConversationService struct {
 	streamManager *natsclient.StreamManager
+	store         ConversationRepository
+	templates     *TemplateService
+	llmClient     llm.Client
 	logger        *logger.Logger
-
-	// In-memory storage for conversations (would be replaced with a database in production)
-	conversations map[string]*model.Conversation
-	mu            sync.RWMutex
 }
 
-// NewConversationService creates a new conversation service.
-func NewConversationService(streamManager *natsclient.StreamManager, log *logger.Logger) *ConversationService {
+// NewConversationService creates a new conversation service. The LLM client
+// is used to validate generation profiles and may be nil.
+func NewConversationService(
+	streamManager *natsclient.StreamManager,
+	store ConversationRepository,
+	templates *TemplateService,
+	llmClient llm.Client,
+	log *logger.Logger,
+) *ConversationService {
 	return &ConversationService{
 		streamManager: streamManager,
+		store:         store,
+		templates:     templates,
+		llmClient:     llmClient,
 		logger:        log,
-		conversations: make(map[string]*model.Conversation),
 	}
 }
 
 // Create creates a new conversation.
 func (s *ConversationService) Create(ctx context.Context, tenantID, userID string, req *model.CreateConversationRequest) (*model.Conversation, error) {
+	profile, templateRef, err := s.resolveProfile(ctx, tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validateProfile(profile); err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 
 	conv := &model.Conversation{
@@ -45,14 +94,19 @@ func (s *ConversationService) Create(ctx context.Context, tenantID, userID strin
 		CreatedAt: now,
 		UpdatedAt: now,
 		Metadata:  req.Metadata,
+		Profile:   profile,
+		Template:  templateRef,
 	}
 
-	s.mu.Lock()
-	s.conversations[conv.ID] = conv
-	s.mu.Unlock()
+	if _, err := s.store.Create(ctx, conv); err != nil {
+		return nil, fmt.Errorf("failed to store conversation: %w", err)
+	}
+
+	s.publishLifecycle(ctx, model.LifecycleCreated, conv)
 
 	s.logger.Info("conversation created",
-		logger.Global().With().Logger.Sugar().Infow("", "conversation_id", conv.ID, "tenant_id", tenantID).Desugar().Check(0, "").Entry,
+		zap.String("conversation_id", conv.ID),
+		zap.String("tenant_id", tenantID),
 	)
 
 	return conv, nil
@@ -60,37 +114,32 @@ func (s *ConversationService) Create(ctx context.Context, tenantID, userID strin
 
 // Get retrieves a conversation by ID.
 func (s *ConversationService) Get(ctx context.Context, tenantID, conversationID string) (*model.Conversation, error) {
-	s.mu.RLock()
-	conv, exists := s.conversations[conversationID]
-	s.mu.RUnlock()
-
-	if !exists {
-		return nil, fmt.Errorf("conversation not found")
-	}
-
-	if conv.TenantID != tenantID {
-		return nil, fmt.Errorf("conversation not found")
-	}
-
-	if conv.Deleted {
-		return nil, fmt.Errorf("conversation not found")
+	conv, _, err := s.get(ctx, tenantID, conversationID)
+	if err != nil {
+		return nil, err
 	}
 
 	return conv, nil
 }
 
-// List retrieves conversations for a tenant.
+// List retrieves conversations for a tenant, most recently updated first.
 func (s *ConversationService) List(ctx context.Context, tenantID string, limit, offset int) (*model.ListConversationsResponse, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	stored, err := s.store.List(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
 
-	var convs []model.Conversation
-	for _, conv := range s.conversations {
-		if conv.TenantID == tenantID && !conv.Deleted {
-			convs = append(convs, *conv)
+	convs := make([]model.Conversation, 0, len(stored))
+	for _, conv := range stored {
+		if !conv.Deleted {
+			convs = append(convs, conv)
 		}
 	}
 
+	sort.Slice(convs, func(i, j int) bool {
+		return convs[i].UpdatedAt.After(convs[j].UpdatedAt)
+	})
+
 	// Simple pagination
 	total := len(convs)
 	start := offset
@@ -111,66 +160,157 @@ func (s *ConversationService) List(ctx context.Context, tenantID string, limit,
 
 // Update updates a conversation.
 func (s *ConversationService) Update(ctx context.Context, tenantID, conversationID string, req *model.UpdateConversationRequest) (*model.Conversation, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	conv, exists := s.conversations[conversationID]
-	if !exists {
-		return nil, fmt.Errorf("conversation not found")
+	if err := s.validateProfile(req.Profile); err != nil {
+		return nil, err
 	}
 
-	if conv.TenantID != tenantID {
-		return nil, fmt.Errorf("conversation not found")
+	conv, err := s.mutate(ctx, tenantID, conversationID, func(conv *model.Conversation) bool {
+		if req.Title != "" {
+			conv.Title = req.Title
+		}
+		if req.Metadata != nil {
+			conv.Metadata = req.Metadata
+		}
+		if req.Profile != nil {
+			conv.Profile = req.Profile
+		}
+		conv.UpdatedAt = time.Now()
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if req.Title != "" {
-		conv.Title = req.Title
-	}
-	if req.Metadata != nil {
-		conv.Metadata = req.Metadata
-	}
-	conv.UpdatedAt = time.Now()
+	s.publishLifecycle(ctx, model.LifecycleUpdated, conv)
 
 	return conv, nil
 }
 
 // Delete soft deletes a conversation.
 func (s *ConversationService) Delete(ctx context.Context, tenantID, conversationID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	conv, err := s.mutate(ctx, tenantID, conversationID, func(conv *model.Conversation) bool {
+		conv.Deleted = true
+		conv.UpdatedAt = time.Now()
+		return true
+	})
+	if err != nil {
+		return err
+	}
 
-	conv, exists := s.conversations[conversationID]
-	if !exists {
-		return fmt.Errorf("conversation not found")
+	s.publishLifecycle(ctx, model.LifecycleDeleted, conv)
+
+	return nil
+}
+
+// UpdateLastMessage updates the last message for a conversation. Messages at or
+// below the conversation's projected sequence have already been counted. The
+// preview never carries the model's thinking.
+func (s *ConversationService) UpdateLastMessage(ctx context.Context, tenantID, conversationID string, msg *model.Message) error {
+	_, err := s.mutate(ctx, tenantID, conversationID, func(conv *model.Conversation) bool {
+		if msg.Sequence <= conv.LastSequence {
+			return false
+		}
+		conv.LastMessage = msg.WithoutThinking()
+		conv.LastSequence = msg.Sequence
+		conv.MessageCount++
+		conv.UpdatedAt = time.Now()
+		return true
+	})
+	return err
+}
+
+// resolveProfile renders the template a new conversation references into its
+// profile's system prompt, pinning the template version used.
+func (s *ConversationService) resolveProfile(ctx context.Context, tenantID string, req *model.CreateConversationRequest) (*model.GenerationProfile, *model.TemplateRef, error) {
+	if req.Template == nil {
+		return req.Profile, nil, nil
 	}
 
-	if conv.TenantID != tenantID {
-		return fmt.Errorf("conversation not found")
+	profile := &model.GenerationProfile{}
+	if req.Profile != nil {
+		if req.Profile.SystemPrompt != "" {
+			return nil, nil, fmt.Errorf("%w: system_prompt and template are mutually exclusive", ErrInvalidProfile)
+		}
+		*profile = *req.Profile
+	}
+
+	prompt, ref, err := s.templates.Render(ctx, tenantID, req.Template)
+	if err != nil {
+		return nil, nil, err
+	}
+	profile.SystemPrompt = prompt
+
+	return profile, ref, nil
+}
+
+// validateProfile checks a generation profile against the LLM provider.
+func (s *ConversationService) validateProfile(profile *model.GenerationProfile) error {
+	if profile == nil || s.llmClient == nil {
+		return nil
 	}
 
-	conv.Deleted = true
-	conv.UpdatedAt = time.Now()
+	if err := s.llmClient.Validate(profileRequest(profile)); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProfile, err)
+	}
 
 	return nil
 }
 
-// UpdateLastMessage updates the last message for a conversation.
-func (s *ConversationService) UpdateLastMessage(ctx context.Context, tenantID, conversationID string, msg *model.Message) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// publishLifecycle records a metadata change on the stream. The KV write has
+// already succeeded, so a failure here only delays a future rebuild.
+func (s *ConversationService) publishLifecycle(ctx context.Context, action model.LifecycleAction, conv *model.Conversation) {
+	_, err := s.streamManager.PublishLifecycle(ctx, &model.ConversationLifecycleEvent{
+		Action:       action,
+		Conversation: *conv,
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		s.logger.Warn("failed to publish lifecycle event",
+			zap.String("conversation_id", conv.ID),
+			zap.String("action", string(action)),
+			zap.Error(err),
+		)
+	}
+}
 
-	conv, exists := s.conversations[conversationID]
-	if !exists {
-		return fmt.Errorf("conversation not found")
+// get loads a live conversation and its revision, hiding other tenants' and deleted conversations.
+func (s *ConversationService) get(ctx context.Context, tenantID, conversationID string) (*model.Conversation, uint64, error) {
+	conv, rev, err := s.store.Get(ctx, tenantID, conversationID)
+	if err != nil {
+		if errors.Is(err, natsclient.ErrNotFound) {
+			return nil, 0, ErrConversationNotFound
+		}
+		return nil, 0, err
 	}
 
-	if conv.TenantID != tenantID {
-		return fmt.Errorf("conversation not found")
+	if conv.TenantID != tenantID || conv.Deleted {
+		return nil, 0, ErrConversationNotFound
 	}
 
-	conv.LastMessage = msg
-	conv.MessageCount++
-	conv.UpdatedAt = time.Now()
+	return conv, rev, nil
+}
 
-	return nil
+// mutate applies fn to the latest revision of a conversation, retrying when
+// another writer got there first. fn reports whether it changed anything.
+func (s *ConversationService) mutate(ctx context.Context, tenantID, conversationID string, fn func(*model.Conversation) bool) (*model.Conversation, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		conv, rev, err := s.get(ctx, tenantID, conversationID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !fn(conv) {
+			return conv, nil
+		}
+
+		_, err = s.store.Update(ctx, conv, rev)
+		if err == nil {
+			return conv, nil
+		}
+		if !errors.Is(err, natsclient.ErrRevisionMismatch) {
+			return nil, fmt.Errorf("failed to update conversation: %w", err)
+		}
+	}
+
+	return nil, ErrConversationConflict
 }
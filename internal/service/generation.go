@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+	natsclient "github.com/capitalize-ai/conversational-platform/internal/nats"
+	"github.com/capitalize-ai/conversational-platform/pkg/logger"
+)
+
+// tokenRecorder buffers the tokens of an in-progress generation in the
+// generation KV bucket. Writes are coalesced by a single flusher so the LLM
+// stream never waits on a KV round trip.
+type tokenRecorder struct {
+	buffer         *natsclient.GenerationBuffer
+	logger         *logger.Logger
+	tenantID       string
+	conversationID string
+
+	mu      sync.Mutex
+	partial model.PartialGeneration
+
+	dirty   chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// newTokenRecorder starts recording a generation for a conversation.
+func newTokenRecorder(buffer *natsclient.GenerationBuffer, log *logger.Logger, tenantID, conversationID, messageID string, attempt int) *tokenRecorder {
+	now := time.Now()
+	r := &tokenRecorder{
+		buffer:         buffer,
+		logger:         log,
+		tenantID:       tenantID,
+		conversationID: conversationID,
+		partial: model.PartialGeneration{
+			MessageID: messageID,
+			Attempt:   attempt,
+			StartedAt: now,
+			UpdatedAt: now,
+		},
+		dirty:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go r.flushLoop()
+
+	return r
+}
+
+// Append records a token and schedules a flush.
+func (r *tokenRecorder) Append(token string) {
+	r.mu.Lock()
+	r.partial.Tokens = append(r.partial.Tokens, token)
+	r.partial.UpdatedAt = time.Now()
+	r.mu.Unlock()
+
+	r.schedule()
+}
+
+// Restart records a new attempt that keeps only the first keep tokens.
+func (r *tokenRecorder) Restart(attempt, keep int) {
+	r.mu.Lock()
+	r.partial.Attempt = attempt
+	r.partial.Tokens = r.partial.Tokens[:min(keep, len(r.partial.Tokens))]
+	r.partial.UpdatedAt = time.Now()
+	r.mu.Unlock()
+
+	r.schedule()
+}
+
+func (r *tokenRecorder) schedule() {
+	select {
+	case r.dirty <- struct{}{}:
+	default:
+	}
+}
+
+// Content returns the text recorded so far and the number of tokens it spans.
+func (r *tokenRecorder) Content() (string, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return strings.Join(r.partial.Tokens, ""), len(r.partial.Tokens)
+}
+
+// Close stops flushing and removes the buffered generation, which is either
+// persisted as a message or abandoned by now.
+func (r *tokenRecorder) Close() {
+	close(r.done)
+	<-r.stopped
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := r.buffer.Delete(ctx, r.tenantID, r.conversationID, r.partial.MessageID); err != nil {
+		r.logger.Warn("failed to clear partial generation",
+			zap.String("conversation_id", r.conversationID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (r *tokenRecorder) flushLoop() {
+	defer close(r.stopped)
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-r.dirty:
+			r.flush()
+		}
+	}
+}
+
+func (r *tokenRecorder) flush() {
+	r.mu.Lock()
+	snapshot := r.partial
+	snapshot.Tokens = append([]string(nil), r.partial.Tokens...)
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := r.buffer.Put(ctx, r.tenantID, r.conversationID, &snapshot); err != nil {
+		r.logger.Debug("failed to buffer partial generation",
+			zap.String("conversation_id", r.conversationID),
+			zap.Error(err),
+		)
+	}
+}
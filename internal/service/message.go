@@ -2,11 +2,16 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 
 	"github.com/capitalize-ai/conversational-platform/internal/llm"
 	"github.com/capitalize-ai/conversational-platform/internal/model"
@@ -15,35 +20,83 @@ import (
 	"github.com/capitalize-ai/conversational-platform/pkg/metrics"
 )
 
-// ErrLLMNotConfigured is returned when no LLM client is available.
-var ErrLLMNotConfigured = errors.New("LLM service not configured: set ANTHROPIC_API_KEY or OPENAI_API_KEY")
+var (
+	// ErrLLMNotConfigured is returned when no LLM client is available.
+	ErrLLMNotConfigured = errors.New("LLM service not configured: set ANTHROPIC_API_KEY or OPENAI_API_KEY")
+
+	// ErrLLMStreamFailed is returned when the provider fails a generation.
+	ErrLLMStreamFailed = errors.New("LLM stream failed")
+
+	// ErrGenerationCancelled is the cancellation cause of a generation stopped via the API.
+	ErrGenerationCancelled = errors.New("generation cancelled")
+)
+
+// StopReasonCancelled is the stop reason recorded on messages cut short by a cancel.
+const StopReasonCancelled = "cancelled"
+
+// StopReasonToolLimit is the stop reason recorded on a reply that still asked
+// for tools after the generation ran out of tool-calling rounds.
+const StopReasonToolLimit = "tool_limit"
+
+// maxCompletionTokens caps the length of a generated reply when the
+// conversation's profile does not.
+const maxCompletionTokens = 4096
 
 // MessageService handles message operations.
 type MessageService struct {
 	streamManager       *natsclient.StreamManager
+	liveRelay           *natsclient.LiveRelay
+	generations         *natsclient.GenerationBuffer
+	cancels             *natsclient.CancelStore
 	conversationService *ConversationService
+	summarizer          *Summarizer
+	tools               *ToolService
+	attachments         *AttachmentService
+	contextBuilder      *ContextBuilder
 	llmClient           llm.Client
+	fallbacks           llm.FallbackChains
+	thinkingHidden      map[string]bool
 	logger              *logger.Logger
 }
 
-// NewMessageService creates a new message service.
+// NewMessageService creates a new message service. Extended thinking is
+// persisted for every tenant but hidden from the users of thinkingHidden.
 func NewMessageService(
 	streamManager *natsclient.StreamManager,
+	liveRelay *natsclient.LiveRelay,
+	generations *natsclient.GenerationBuffer,
+	cancels *natsclient.CancelStore,
 	conversationService *ConversationService,
+	summarizer *Summarizer,
+	tools *ToolService,
+	attachments *AttachmentService,
 	llmClient llm.Client,
+	fallbacks llm.FallbackChains,
+	thinkingHidden []string,
 	log *logger.Logger,
 ) *MessageService {
+	hidden := make(map[string]bool, len(thinkingHidden))
+	for _, tenantID := range thinkingHidden {
+		hidden[tenantID] = true
+	}
+
 	return &MessageService{
 		streamManager:       streamManager,
+		liveRelay:           liveRelay,
+		generations:         generations,
+		cancels:             cancels,
 		conversationService: conversationService,
+		summarizer:          summarizer,
+		tools:               tools,
+		attachments:         attachments,
+		contextBuilder:      NewContextBuilder(streamManager, summarizer, attachments),
 		llmClient:           llmClient,
+		fallbacks:           fallbacks,
+		thinkingHidden:      hidden,
 		logger:              log,
 	}
 }
 
-// TokenCallback is called for each token during streaming.
-type TokenCallback func(token string, index int) error
-
 // Send sends a user message and generates an AI response.
 func (s *MessageService) Send(ctx context.Context, tenantID, conversationID string, req *model.SendMessageRequest) (*model.Message, uint64, error) {
 	now := time.Now()
@@ -57,6 +110,9 @@ func (s *MessageService) Send(ctx context.Context, tenantID, conversationID stri
 		Content:        req.Content,
 		CreatedAt:      now,
 	}
+	if len(req.ContentBlocks) > 0 {
+		userMsg.SetBlocks(req.ContentBlocks)
+	}
 
 	// Publish user message
 	seq, err := s.streamManager.PublishMessage(ctx, userMsg)
@@ -74,102 +130,734 @@ func (s *MessageService) Send(ctx context.Context, tenantID, conversationID stri
 	return userMsg, seq, nil
 }
 
-// SendWithStream sends a user message and streams the AI response.
-func (s *MessageService) SendWithStream(
-	ctx context.Context,
-	tenantID, conversationID string,
-	req *model.SendMessageRequest,
-	onToken TokenCallback,
-) (*model.Message, *model.Message, error) {
-	// Send user message
+// Enqueue sends a user message and queues generation of the reply for a worker.
+// The output is published on the conversation's live subjects.
+func (s *MessageService) Enqueue(ctx context.Context, tenantID, conversationID string, req *model.SendMessageRequest) (*model.Message, *model.GenerationJob, error) {
+	if err := s.ValidateModel(req.Model); err != nil {
+		return nil, nil, err
+	}
+	if err := s.ValidateResponseFormat(req.ResponseFormat); err != nil {
+		return nil, nil, err
+	}
+
 	userMsg, _, err := s.Send(ctx, tenantID, conversationID, req)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	job := newGenerationJob(userMsg, req)
+	if _, err := s.streamManager.PublishJob(ctx, job); err != nil {
+		return userMsg, nil, fmt.Errorf("failed to enqueue generation: %w", err)
+	}
+
+	return userMsg, job, nil
+}
+
+// ResolveAttachments checks that the attachments a message references were
+// uploaded to its conversation and records their names and media types.
+func (s *MessageService) ResolveAttachments(ctx context.Context, tenantID, conversationID string, req *model.SendMessageRequest) error {
+	return s.attachments.Resolve(ctx, tenantID, conversationID, req.ContentBlocks)
+}
+
+// ValidateModel checks that a configured provider serves a requested model,
+// returning an error matching llm.ErrUnknownModel if none does. An empty
+// model selects the default and is always valid.
+func (s *MessageService) ValidateModel(modelName string) error {
+	if modelName == "" || s.llmClient == nil {
+		return nil
+	}
+
+	if resolver, ok := s.llmClient.(llm.Resolver); ok {
+		_, _, err := resolver.Resolve(modelName)
+		return err
+	}
+
+	if !slices.Contains(s.llmClient.Models(), modelName) {
+		return fmt.Errorf("%w: %q", llm.ErrUnknownModel, modelName)
+	}
+
+	return nil
+}
+
+// ValidateResponseFormat checks that a requested response format's schema is
+// one replies can be validated against. A nil format is always valid.
+func (s *MessageService) ValidateResponseFormat(format *model.ResponseFormat) error {
+	if format == nil {
+		return nil
+	}
+
+	_, err := parseSchema(format.JSONSchema.Schema)
+	return err
+}
+
+// newGenerationJob creates the job that generates the reply to userMsg. The
+// assistant message ID is allocated up front so relayed tokens and the
+// persisted message share it.
+func newGenerationJob(userMsg *model.Message, req *model.SendMessageRequest) *model.GenerationJob {
+	return &model.GenerationJob{
+		ID:                 uuid.Must(uuid.NewV7()).String(),
+		TenantID:           userMsg.TenantID,
+		ConversationID:     userMsg.ConversationID,
+		UserMessageID:      userMsg.ID,
+		UserSequence:       userMsg.Sequence,
+		AssistantMessageID: uuid.Must(uuid.NewV7()).String(),
+		Model:              req.Model,
+		CreatedAt:          time.Now(),
+		ResponseFormat:     req.ResponseFormat,
+	}
+}
+
+// completionRequest returns the request for the next turn of a conversation:
+// its generation profile, the tenant's fallback chain and, if set, the model
+// the user picked for this turn.
+func (s *MessageService) completionRequest(conv *model.Conversation, modelName string) *llm.CompletionRequest {
+	req := profileRequest(conv.Profile)
+	req.Fallbacks = s.fallbacks.For(conv.TenantID)
+	if modelName != "" {
+		req.Model = modelName
+	}
+	if req.Model == "" {
+		req.Model = llm.DefaultModel(s.llmClient)
+	}
+	if req.MaxTokens == 0 {
+		// Reasoning counts against max_tokens, so leave the reply its usual room
+		req.MaxTokens = maxCompletionTokens + req.ThinkingBudget
+	}
+	return req
+}
+
+// CheckAvailable returns an error matching llm.ErrCircuitOpen if every model
+// that could serve the next turn of a conversation has an open circuit
+// breaker, so the turn can be refused before anything is queued.
+func (s *MessageService) CheckAvailable(ctx context.Context, tenantID, conversationID, modelName string) error {
+	checker, ok := s.llmClient.(llm.Checker)
+	if !ok {
+		return nil
+	}
+
+	conv, err := s.conversationService.Get(ctx, tenantID, conversationID)
+	if err != nil {
+		return err
+	}
+
+	return checker.Check(s.completionRequest(conv, modelName))
+}
+
+// Generate streams the assistant reply for a job from the LLM, relaying tokens
+// to live subscribers and persisting the final message.
+func (s *MessageService) Generate(ctx context.Context, job *model.GenerationJob) (*model.Message, error) {
+	tenantID, conversationID := job.TenantID, job.ConversationID
+
 	// Check if LLM client is available
 	if s.llmClient == nil {
-		s.logger.Error("LLM client not configured", "conversation_id", conversationID)
-		return userMsg, nil, ErrLLMNotConfigured
+		s.logger.Error("LLM client not configured", zap.String("conversation_id", conversationID))
+		return nil, ErrLLMNotConfigured
 	}
 
-	// Get conversation history for context
-	messages, _, _, err := s.streamManager.GetMessages(ctx, tenantID, conversationID, 0, 50)
+	// Any replica can cancel this generation by signalling over NATS. Listen
+	// before any slow work, then catch a cancel that was recorded on the job
+	// before the subscription was open.
+	genCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	stopCancel, err := s.liveRelay.OnCancel(tenantID, conversationID, func() {
+		cancel(ErrGenerationCancelled)
+	})
 	if err != nil {
-		return userMsg, nil, fmt.Errorf("failed to get message history: %w", err)
+		return nil, err
+	}
+	defer stopCancel()
+
+	if marked, err := s.cancels.Marked(ctx, tenantID, job.ID); err != nil {
+		s.logger.Warn("failed to check job cancellation", zap.String("job_id", job.ID), zap.Error(err))
+	} else if marked {
+		cancel(ErrGenerationCancelled)
+	}
+
+	conv, err := s.conversationService.Get(ctx, tenantID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	req := s.completionRequest(conv, job.Model)
+	req.Stream = true
+	req.OnFailover = func(failover llm.Failover) {
+		s.publishFailoverEvent(ctx, job, failover)
+	}
+	modelName := req.Model
+
+	var schema *jsonSchema
+	if job.ResponseFormat != nil {
+		schema, err = parseSchema(job.ResponseFormat.JSONSchema.Schema)
+		if err != nil {
+			return nil, err
+		}
+		req.ResponseFormat = llmResponseFormat(job.ResponseFormat)
 	}
 
-	// Convert to LLM format
-	chatMessages := make([]llm.ChatMessage, len(messages))
-	for i, msg := range messages {
-		chatMessages[i] = llm.ChatMessage{
-			Role:    string(msg.Role),
-			Content: msg.Content,
+	if s.tools.config.MaxIterations > 0 {
+		req.Tools, err = s.tools.Definitions(ctx, tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tools: %w", err)
 		}
 	}
 
+	// Fill the context window with the newest history, leaving room for the
+	// system prompt, tool and response format declarations and the reply
+	budget := llm.ContextWindow(modelName) - req.MaxTokens - llm.EstimateToolTokens(modelName, req.Tools) - llm.EstimateFormatTokens(modelName, req.ResponseFormat)
+	if req.System != "" {
+		budget -= llm.EstimateTokens(modelName, llm.ChatMessage{Role: string(model.RoleSystem), Content: req.System})
+	}
+	req.Messages, err = s.contextBuilder.Build(ctx, tenantID, conversationID, job.UserSequence, modelName, budget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build context: %w", err)
+	}
+
+	// A redelivered job picks up where an earlier delivery left off
+	assistantID := job.AssistantMessageID
+	persisted, err := s.persistedMessages(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if reply, ok := persisted[assistantID]; ok {
+		// The turn was finished but the job never acknowledged
+		return reply, nil
+	}
+
+	// Each streaming of the reply has its own attempt number, so followers
+	// start over when a redelivery or a schema retry streams it again
+	attempt := (max(job.Attempt, 1)-1)*maxSchemaAttempts + 1
+
 	// Stream from LLM
-	streamStart := time.Now()
-	modelName := req.Model
-	if modelName == "" {
-		modelName = "claude-3-5-sonnet-20241022"
+	recorder := newTokenRecorder(s.generations, s.logger, tenantID, conversationID, assistantID, attempt)
+	defer recorder.Close()
+
+	// Each round that ends in tool calls is persisted under an ID derived from
+	// the job and followed by the results; the reply that ends the turn takes
+	// the job's. Rounds persisted by an earlier delivery are not generated again.
+	// Tokens of every round are relayed under the job's ID with continuing
+	// indexes, so followers see a single stream for the turn. Thinking deltas
+	// are relayed the same way but indexed on their own and not recorded.
+	emitted, thought, attempts := 0, 0, 0
+	req.OnThinking = func(delta string) error {
+		event := &model.TokenEvent{MessageID: assistantID, Attempt: attempt, Token: delta, Index: thought, Thinking: true}
+		thought++
+		if err := s.liveRelay.PublishToken(tenantID, conversationID, event); err != nil {
+			s.logger.Debug("failed to relay thinking", zap.String("conversation_id", conversationID), zap.Error(err))
+		}
+		return nil
+	}
+	for round := 0; ; round++ {
+		if msg, ok := persisted[roundMessageID(job, round)]; ok {
+			chatMsg := chatMessage(msg)
+			results, err := s.runTools(ctx, genCtx, job, round, chatMsg.ToolCalls, persisted)
+			if err != nil {
+				return nil, err
+			}
+			req.Messages = append(append(req.Messages, chatMsg), results...)
+			continue
+		}
+
+		streamStart := time.Now()
+
+		var tokens []string
+		offset := emitted
+		resp, err := s.llmClient.CompleteStream(genCtx, req, func(token string, index int) error {
+			event := &model.TokenEvent{MessageID: assistantID, Attempt: attempt, Token: token, Index: offset + index}
+			recorder.Append(token)
+			tokens = append(tokens, token)
+			if err := s.liveRelay.PublishToken(tenantID, conversationID, event); err != nil {
+				s.logger.Debug("failed to relay token", zap.String("conversation_id", conversationID), zap.Error(err))
+			}
+			return nil
+		})
+		emitted += len(tokens)
+
+		cancelled := err != nil && errors.Is(context.Cause(genCtx), ErrGenerationCancelled)
+		if cancelled {
+			// Keep whatever was generated before the cancel
+			resp = &llm.CompletionResponse{
+				Content:         strings.Join(tokens, ""),
+				Model:           modelName,
+				TokensIn:        llm.EstimateRequestTokens(req),
+				TokensOut:       len(tokens),
+				StopReason:      StopReasonCancelled,
+				LatencyMs:       time.Since(streamStart).Milliseconds(),
+				TokensEstimated: true,
+			}
+		} else if err != nil {
+			// The worker reports the failure once it knows the job will not be retried
+			return nil, fmt.Errorf("%w: %w", ErrLLMStreamFailed, err)
+		}
+
+		final := cancelled || len(resp.ToolCalls) == 0 || round >= s.tools.config.MaxIterations
+		messageID := assistantID
+		if !final {
+			messageID = roundMessageID(job, round)
+		} else if len(resp.ToolCalls) > 0 {
+			// Out of rounds: keep the text and drop calls that will never be answered
+			resp.ToolCalls = nil
+			resp.StopReason = StopReasonToolLimit
+		}
+
+		// A final reply that does not match the response format is generated
+		// once more, with the violations; the rejected reply is not persisted
+		var validation *model.SchemaValidation
+		if schema != nil && final && !cancelled {
+			attempts++
+			violations := schema.validate(resp.Content)
+			validation = &model.SchemaValidation{Valid: len(violations) == 0, Attempts: attempts, Errors: violations}
+
+			if !validation.Valid && attempts < maxSchemaAttempts && resp.StopReason != StopReasonToolLimit {
+				metrics.RecordLLMStream(resp.Model, "schema_violation", float64(resp.LatencyMs)/1000.0, resp.TokensIn, resp.TokensOut)
+
+				// The retry replaces the rejected reply from its first token
+				attempt++
+				emitted = offset
+				recorder.Restart(attempt, emitted)
+				s.publishSchemaRetryEvent(ctx, job, attempts, violations, emitted)
+
+				req.Messages = append(req.Messages,
+					llm.ChatMessage{Role: string(model.RoleAssistant), Content: resp.Content},
+					llm.ChatMessage{Role: string(model.RoleUser), Content: schemaRetryPrompt(violations)},
+				)
+				// The retry is not a tool round
+				round--
+				continue
+			}
+		}
+
+		assistantMsg, err := s.publishReply(ctx, job, messageID, resp, streamStart, validation)
+		if err != nil {
+			return nil, err
+		}
+
+		status := "success"
+		if cancelled {
+			status = StopReasonCancelled
+			s.publishCancelEvent(ctx, job, resp.TokensOut)
+		}
+
+		// Track metrics
+		metrics.RecordLLMStream(resp.Model, status, float64(resp.LatencyMs)/1000.0, resp.TokensIn, resp.TokensOut)
+		metrics.RecordLLMCacheTokens(resp.Model, resp.CacheReadTokens, resp.CacheWriteTokens)
+
+		if final {
+			return assistantMsg, nil
+		}
+
+		results, err := s.runTools(ctx, genCtx, job, round, resp.ToolCalls, persisted)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = append(append(req.Messages, chatMessage(assistantMsg)), results...)
+	}
+}
+
+// jobMessageNamespace scopes the name-based IDs of messages a job persists.
+var jobMessageNamespace = uuid.MustParse("5f3b8a2e-9c4d-4e71-b0a6-d2c8e4f1a937")
+
+// roundMessageID returns the ID of the assistant message ending a tool round
+// of a job. IDs derive from the job so a redelivery republishes the same ones,
+// which JetStream deduplicates.
+func roundMessageID(job *model.GenerationJob, round int) string {
+	return uuid.NewSHA1(jobMessageNamespace, []byte(fmt.Sprintf("%s/round/%d", job.ID, round))).String()
+}
+
+// toolMessageID returns the ID of the message answering a tool call made in
+// a round of a job.
+func toolMessageID(job *model.GenerationJob, round int, callID string) string {
+	return uuid.NewSHA1(jobMessageNamespace, []byte(fmt.Sprintf("%s/round/%d/tool/%s", job.ID, round, callID))).String()
+}
+
+// persistedMessages returns the assistant and tool messages persisted after a
+// job's user message, by ID. Only a redelivered job can find any.
+func (s *MessageService) persistedMessages(ctx context.Context, job *model.GenerationJob) (map[string]*model.Message, error) {
+	persisted := make(map[string]*model.Message)
+	if job.Attempt <= 1 {
+		return persisted, nil
 	}
 
-	resp, err := s.llmClient.CompleteStream(ctx, &llm.CompletionRequest{
-		Model:     modelName,
-		Messages:  chatMessages,
-		MaxTokens: 4096,
-		Stream:    true,
-	}, func(token string, index int) error {
-		return onToken(token, index)
+	filter := natsclient.MessageFilter(job.TenantID, job.ConversationID)
+	_, err := s.streamManager.Replay(ctx, []string{filter}, job.UserSequence, func(_ string, sequence uint64, data []byte) error {
+		var msg model.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil
+		}
+		if msg.Role != model.RoleAssistant && msg.Role != model.RoleTool {
+			return nil
+		}
+
+		msg.Sequence = sequence
+		persisted[msg.ID] = &msg
+		return nil
 	})
 	if err != nil {
-		// Publish error event
-		s.streamManager.PublishEvent(ctx, &model.ConversationEvent{
-			ID:             uuid.Must(uuid.NewV7()).String(),
-			ConversationID: conversationID,
-			TenantID:       tenantID,
-			Type:           model.EventTypeError,
-			Reason:         err.Error(),
-			CreatedAt:      time.Now(),
-		})
-		return userMsg, nil, fmt.Errorf("LLM stream failed: %w", err)
+		return nil, fmt.Errorf("failed to read persisted rounds: %w", err)
 	}
 
+	return persisted, nil
+}
+
+// runTools answers the tool calls of a round in order, reusing results an
+// earlier delivery of the job persisted.
+func (s *MessageService) runTools(ctx, genCtx context.Context, job *model.GenerationJob, round int, calls []llm.ToolCall, persisted map[string]*model.Message) ([]llm.ChatMessage, error) {
+	results := make([]llm.ChatMessage, 0, len(calls))
+	for _, call := range calls {
+		toolMsg, ok := persisted[toolMessageID(job, round, call.ID)]
+		if !ok {
+			var err error
+			toolMsg, err = s.runTool(ctx, genCtx, job, round, call)
+			if err != nil {
+				return nil, err
+			}
+		}
+		results = append(results, chatMessage(toolMsg))
+	}
+	return results, nil
+}
+
+// Summarize folds the older turns of a job's conversation, up to its reply,
+// into the rolling summary before the next turn needs them. It runs after the
+// job is acknowledged, so a slow summary neither delays nor redelivers it.
+func (s *MessageService) Summarize(ctx context.Context, job *model.GenerationJob, reply *model.Message) {
+	conv, err := s.conversationService.Get(ctx, job.TenantID, job.ConversationID)
+	if err == nil {
+		err = s.summarizer.Update(ctx, job.TenantID, job.ConversationID, reply.Sequence, s.completionRequest(conv, job.Model).Model)
+	}
+	if err != nil {
+		s.logger.Warn("failed to update conversation summary",
+			zap.String("conversation_id", job.ConversationID),
+			zap.Error(err),
+		)
+	}
+}
+
+// publishReply persists an assistant message generated for a job, along with
+// its schema validation if the job asked for a response format.
+func (s *MessageService) publishReply(ctx context.Context, job *model.GenerationJob, messageID string, resp *llm.CompletionResponse, streamStart time.Time, validation *model.SchemaValidation) (*model.Message, error) {
 	streamEnd := time.Now()
 
 	// Create assistant message
 	assistantMsg := &model.Message{
-		ID:             uuid.Must(uuid.NewV7()).String(),
-		ConversationID: conversationID,
-		TenantID:       tenantID,
-		Role:           model.RoleAssistant,
-		Content:        resp.Content,
-		Model:          &resp.Model,
-		TokensIn:       &resp.TokensIn,
-		TokensOut:      &resp.TokensOut,
-		LatencyMs:      &resp.LatencyMs,
-		StopReason:     &resp.StopReason,
-		CreatedAt:      time.Now(),
-		StreamStarted:  &streamStart,
-		StreamEnded:    &streamEnd,
+		ID:               messageID,
+		ConversationID:   job.ConversationID,
+		TenantID:         job.TenantID,
+		Role:             model.RoleAssistant,
+		Provider:         optionalString(resp.Provider),
+		Model:            &resp.Model,
+		TokensIn:         &resp.TokensIn,
+		TokensOut:        &resp.TokensOut,
+		LatencyMs:        &resp.LatencyMs,
+		StopReason:       &resp.StopReason,
+		CacheReadTokens:  optionalInt(resp.CacheReadTokens),
+		CacheWriteTokens: optionalInt(resp.CacheWriteTokens),
+		TokensEstimated:  resp.TokensEstimated,
+		SchemaValidation: validation,
+		CreatedAt:        time.Now(),
+		StreamStarted:    &streamStart,
+		StreamEnded:      &streamEnd,
 	}
+	assistantMsg.SetBlocks(replyBlocks(resp))
 
 	// Publish assistant message
 	seq, err := s.streamManager.PublishMessage(ctx, assistantMsg)
 	if err != nil {
-		return userMsg, nil, fmt.Errorf("failed to publish assistant message: %w", err)
+		return nil, fmt.Errorf("failed to publish assistant message: %w", err)
 	}
 	assistantMsg.Sequence = seq
 
 	// Update conversation
-	s.conversationService.UpdateLastMessage(ctx, tenantID, conversationID, assistantMsg)
+	s.conversationService.UpdateLastMessage(ctx, job.TenantID, job.ConversationID, assistantMsg)
+	metrics.MessagesTotal.WithLabelValues(job.TenantID, string(model.RoleAssistant)).Inc()
 
-	// Track metrics
-	metrics.MessagesTotal.WithLabelValues(tenantID, string(model.RoleAssistant)).Inc()
-	metrics.RecordLLMStream(resp.Model, "success", resp.LatencyMs/1000.0, resp.TokensIn, resp.TokensOut)
+	return assistantMsg, nil
+}
+
+// runTool executes a tool call made during a generation and persists its
+// result as a tool message, reporting progress to followers as it goes. The
+// call itself runs under genCtx so cancelling the generation aborts it.
+func (s *MessageService) runTool(ctx, genCtx context.Context, job *model.GenerationJob, round int, call llm.ToolCall) (*model.Message, error) {
+	s.publishToolEvent(ctx, job, model.EventTypeToolCall, map[string]any{
+		"message_id":   job.AssistantMessageID,
+		"tool_call_id": call.ID,
+		"tool":         call.Name,
+		"arguments":    call.Arguments,
+		"round":        round,
+	})
+
+	start := time.Now()
+	result, isError := s.tools.Execute(genCtx, job.TenantID, job.ConversationID, call)
+	latency := time.Since(start).Milliseconds()
+
+	toolMsg := &model.Message{
+		ID:             toolMessageID(job, round, call.ID),
+		ConversationID: job.ConversationID,
+		TenantID:       job.TenantID,
+		Role:           model.RoleTool,
+		ToolName:       call.Name,
+		LatencyMs:      &latency,
+		CreatedAt:      time.Now(),
+	}
+	toolMsg.SetBlocks([]model.ContentBlock{{
+		Type:      model.ContentBlockToolResult,
+		ToolUseID: call.ID,
+		Content:   []model.ContentBlock{{Type: model.ContentBlockText, Text: result}},
+		IsError:   isError,
+	}})
+
+	seq, err := s.streamManager.PublishMessage(ctx, toolMsg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish tool message: %w", err)
+	}
+	toolMsg.Sequence = seq
+
+	s.conversationService.UpdateLastMessage(ctx, job.TenantID, job.ConversationID, toolMsg)
+	metrics.MessagesTotal.WithLabelValues(job.TenantID, string(model.RoleTool)).Inc()
+
+	s.publishToolEvent(ctx, job, model.EventTypeToolResult, map[string]any{
+		"message_id":      job.AssistantMessageID,
+		"tool_call_id":    call.ID,
+		"tool":            call.Name,
+		"tool_message_id": toolMsg.ID,
+		"is_error":        isError,
+		"latency_ms":      latency,
+	})
+
+	return toolMsg, nil
+}
+
+// publishToolEvent records the progress of a tool call.
+func (s *MessageService) publishToolEvent(ctx context.Context, job *model.GenerationJob, eventType model.EventType, metadata map[string]any) {
+	_, err := s.streamManager.PublishEvent(ctx, &model.ConversationEvent{
+		ID:             uuid.Must(uuid.NewV7()).String(),
+		ConversationID: job.ConversationID,
+		TenantID:       job.TenantID,
+		Type:           eventType,
+		Metadata:       metadata,
+		CreatedAt:      time.Now(),
+	})
+	if err != nil {
+		s.logger.Error("failed to publish tool event",
+			zap.String("conversation_id", job.ConversationID),
+			zap.Error(err),
+		)
+	}
+}
+
+// publishSchemaRetryEvent records that a reply did not match its response
+// format's schema and is being generated again, streaming from tokenIndex.
+func (s *MessageService) publishSchemaRetryEvent(ctx context.Context, job *model.GenerationJob, attempt int, violations []string, tokenIndex int) {
+	_, err := s.streamManager.PublishEvent(ctx, &model.ConversationEvent{
+		ID:             uuid.Must(uuid.NewV7()).String(),
+		ConversationID: job.ConversationID,
+		TenantID:       job.TenantID,
+		Type:           model.EventTypeSchemaRetry,
+		Reason:         "reply did not match the response format schema",
+		Metadata: map[string]any{
+			"message_id":  job.AssistantMessageID,
+			"attempt":     attempt,
+			"errors":      violations,
+			"token_index": tokenIndex,
+		},
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		s.logger.Error("failed to publish schema retry event",
+			zap.String("conversation_id", job.ConversationID),
+			zap.Error(err),
+		)
+	}
+}
+
+// replyBlocks returns the content blocks of a completion: its thinking, then
+// its text followed by its tool calls.
+func replyBlocks(resp *llm.CompletionResponse) []model.ContentBlock {
+	var blocks []model.ContentBlock
+	for _, block := range resp.Thinking {
+		blocks = append(blocks, model.ContentBlock{
+			Type:      model.ContentBlockType(block.Type),
+			Thinking:  block.Thinking,
+			Signature: block.Signature,
+			Data:      block.Data,
+		})
+	}
+	if resp.Content != "" || len(resp.ToolCalls) == 0 {
+		blocks = append(blocks, model.ContentBlock{Type: model.ContentBlockText, Text: resp.Content})
+	}
+	for _, call := range resp.ToolCalls {
+		blocks = append(blocks, model.ContentBlock{
+			Type:  model.ContentBlockToolUse,
+			ID:    call.ID,
+			Name:  call.Name,
+			Input: call.Arguments,
+		})
+	}
+	return blocks
+}
+
+// Cancel stops the generation in progress for a conversation, wherever it runs.
+// A job still waiting for a worker is marked cancelled so it is dropped when
+// picked up. It reports false if nothing was queued or being generated.
+func (s *MessageService) Cancel(ctx context.Context, tenantID, conversationID string) (bool, error) {
+	queued := false
+	job, err := s.streamManager.PendingJob(ctx, tenantID, conversationID)
+	switch {
+	case err == nil:
+		if err := s.cancels.Mark(ctx, tenantID, job.ID); err != nil {
+			return false, err
+		}
+		queued = true
+	case !errors.Is(err, natsclient.ErrNotFound):
+		return false, err
+	}
+
+	running, err := s.liveRelay.RequestCancel(ctx, tenantID, conversationID)
+	if err != nil {
+		return false, err
+	}
+
+	return queued || running, nil
+}
+
+// Cancelled reports whether a job was cancelled before a worker ran it. The
+// cancel is recorded on the conversation, as for a cancelled generation.
+func (s *MessageService) Cancelled(ctx context.Context, job *model.GenerationJob) (bool, error) {
+	cancelled, err := s.cancels.Marked(ctx, job.TenantID, job.ID)
+	if err != nil || !cancelled {
+		return false, err
+	}
 
-	return userMsg, assistantMsg, nil
+	s.publishCancelEvent(ctx, job, 0)
+	return true, nil
+}
+
+// publishCancelEvent records that a generation was cancelled, whether it was
+// still queued or had produced tokens.
+func (s *MessageService) publishCancelEvent(ctx context.Context, job *model.GenerationJob, tokens int) {
+	_, err := s.streamManager.PublishEvent(ctx, &model.ConversationEvent{
+		ID:             uuid.Must(uuid.NewV7()).String(),
+		ConversationID: job.ConversationID,
+		TenantID:       job.TenantID,
+		Type:           model.EventTypeCancel,
+		Reason:         "generation cancelled by request",
+		Code:           StopReasonCancelled,
+		Metadata: map[string]any{
+			"message_id":       job.AssistantMessageID,
+			"tokens_generated": tokens,
+		},
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		s.logger.Error("failed to publish cancel event",
+			zap.String("conversation_id", job.ConversationID),
+			zap.Error(err),
+		)
+	}
+}
+
+// publishFailoverEvent records a failed generation attempt that is being
+// retried or handed to a fallback model.
+func (s *MessageService) publishFailoverEvent(ctx context.Context, job *model.GenerationJob, failover llm.Failover) {
+	_, code, _ := classifyGenerationError(failover.Err)
+
+	_, err := s.streamManager.PublishEvent(ctx, &model.ConversationEvent{
+		ID:             uuid.Must(uuid.NewV7()).String(),
+		ConversationID: job.ConversationID,
+		TenantID:       job.TenantID,
+		Type:           model.EventTypeFailover,
+		Reason:         failover.Err.Error(),
+		Code:           code,
+		Metadata: map[string]any{
+			"message_id":    job.AssistantMessageID,
+			"attempt":       failover.Attempt,
+			"provider":      failover.Provider,
+			"model":         failover.Model,
+			"next_provider": failover.NextProvider,
+			"next_model":    failover.NextModel,
+			"backoff_ms":    failover.Backoff.Milliseconds(),
+		},
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		s.logger.Error("failed to publish failover event",
+			zap.String("conversation_id", job.ConversationID),
+			zap.Error(err),
+		)
+	}
+}
+
+// optionalString returns a pointer to s, or nil if it is empty.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// optionalInt returns nil for a zero count.
+func optionalInt(n int) *int {
+	if n == 0 {
+		return nil
+	}
+	return &n
+}
+
+// PublishGenerationError records a failed generation on the conversation. The
+// event carries the assistant message ID so followers can tell which
+// generation it ends.
+func (s *MessageService) PublishGenerationError(ctx context.Context, job *model.GenerationJob, err error) {
+	eventType, code, retryAfter := classifyGenerationError(err)
+
+	// The generation context may be what expired; the event must still land
+	_, pubErr := s.streamManager.PublishEvent(context.WithoutCancel(ctx), &model.ConversationEvent{
+		ID:             uuid.Must(uuid.NewV7()).String(),
+		ConversationID: job.ConversationID,
+		TenantID:       job.TenantID,
+		Type:           eventType,
+		Reason:         err.Error(),
+		Code:           code,
+		RetryAfter:     retryAfter,
+		Metadata:       map[string]any{"message_id": job.AssistantMessageID},
+		CreatedAt:      time.Now(),
+	})
+	if pubErr != nil {
+		s.logger.Error("failed to publish generation error",
+			zap.String("conversation_id", job.ConversationID),
+			zap.Error(pubErr),
+		)
+	}
+}
+
+// classifyGenerationError maps a generation failure to the event type, error
+// code and retry hint (in seconds) reported to clients.
+func classifyGenerationError(err error) (model.EventType, string, int) {
+	switch {
+	case errors.Is(err, llm.ErrRateLimited):
+		var perr *llm.ProviderError
+		retryAfter := 0
+		if errors.As(err, &perr) && perr.RetryAfter > 0 {
+			retryAfter = int(math.Ceil(perr.RetryAfter.Seconds()))
+		}
+		return model.EventTypeRateLimit, "rate_limit", retryAfter
+	case errors.Is(err, llm.ErrCircuitOpen):
+		var open *llm.BreakerOpenError
+		retryAfter := 0
+		if errors.As(err, &open) {
+			retryAfter = int(math.Ceil(open.RetryAfter.Seconds()))
+		}
+		return model.EventTypeError, "provider_unavailable", retryAfter
+	case errors.Is(err, llm.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return model.EventTypeTimeout, "timeout", 0
+	case errors.Is(err, llm.ErrAuth):
+		return model.EventTypeError, "provider_auth", 0
+	case errors.Is(err, llm.ErrInvalidRequest):
+		return model.EventTypeError, "invalid_request", 0
+	case errors.Is(err, ErrLLMNotConfigured):
+		return model.EventTypeError, "llm_not_configured", 0
+	default:
+		return model.EventTypeError, "provider_error", 0
+	}
 }
 
 // GetMessages retrieves messages for a conversation.
@@ -185,11 +873,141 @@ func (s *MessageService) GetMessages(ctx context.Context, tenantID, conversation
 	if err != nil {
 		return nil, fmt.Errorf("failed to get messages: %w", err)
 	}
+	if s.thinkingHidden[tenantID] {
+		for i := range messages {
+			messages[i] = *messages[i].WithoutThinking()
+		}
+	}
+
+	partial, err := s.ActiveGeneration(ctx, tenantID, conversationID)
+	if err != nil {
+		s.logger.Debug("failed to check for active generation", zap.String("conversation_id", conversationID), zap.Error(err))
+	}
 
 	return &model.ListMessagesResponse{
 		Messages:     messages,
 		HasMore:      hasMore,
 		LastSequence: lastSeq,
-		StreamActive: false,
+		StreamActive: partial != nil,
 	}, nil
 }
+
+// ActiveGeneration returns the buffered output of the conversation's newest
+// queued or running generation, or nil if there is none or it has not
+// produced any tokens yet.
+func (s *MessageService) ActiveGeneration(ctx context.Context, tenantID, conversationID string) (*model.PartialGeneration, error) {
+	job, err := s.streamManager.PendingJob(ctx, tenantID, conversationID)
+	if err != nil {
+		if errors.Is(err, natsclient.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return s.PartialGeneration(ctx, tenantID, conversationID, job.AssistantMessageID)
+}
+
+// PartialGeneration returns the buffered output of the generation of an
+// assistant message, or nil if it is not being generated.
+func (s *MessageService) PartialGeneration(ctx context.Context, tenantID, conversationID, messageID string) (*model.PartialGeneration, error) {
+	partial, err := s.generations.Get(ctx, tenantID, conversationID, messageID)
+	if err != nil {
+		if errors.Is(err, natsclient.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return partial, nil
+}
+
+// LiveUpdate is a persisted message, event or in-flight token delivered to
+// live subscribers. Exactly one of Message, Event or Token is set.
+type LiveUpdate struct {
+	Sequence uint64
+	Message  *model.Message
+	Event    *model.ConversationEvent
+	Token    *model.TokenEvent
+}
+
+// Follow streams messages and events published to a conversation after
+// afterSequence, along with tokens of any generation in progress, until ctx is
+// cancelled. The channel is closed when the persisted subscription ends.
+func (s *MessageService) Follow(ctx context.Context, tenantID, conversationID string, afterSequence uint64) (<-chan LiveUpdate, error) {
+	// Subscribe to tokens first so none are missed while the consumer starts
+	tokens, err := s.liveRelay.SubscribeTokens(ctx, tenantID, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to follow tokens: %w", err)
+	}
+
+	entries, err := s.streamManager.Follow(ctx, tenantID, conversationID, afterSequence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to follow conversation: %w", err)
+	}
+
+	hidden := s.thinkingHidden[tenantID]
+	updates := make(chan LiveUpdate)
+	go func() {
+		defer close(updates)
+		for {
+			var update LiveUpdate
+			select {
+			case <-ctx.Done():
+				return
+			case token := <-tokens:
+				if token.Thinking && hidden {
+					continue
+				}
+				update = LiveUpdate{Token: &token}
+			case entry, ok := <-entries:
+				if !ok {
+					return
+				}
+				if update, ok = decodeLiveUpdate(entry); !ok {
+					continue
+				}
+				if update.Message != nil && hidden {
+					update.Message = update.Message.WithoutThinking()
+				}
+			}
+
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return updates, nil
+}
+
+// decodeLiveUpdate converts a raw stream entry into a LiveUpdate.
+func decodeLiveUpdate(entry natsclient.StreamEntry) (LiveUpdate, bool) {
+	info, ok := natsclient.ParseSubject(entry.Subject)
+	if !ok {
+		return LiveUpdate{}, false
+	}
+
+	update := LiveUpdate{Sequence: entry.Sequence}
+	switch info.Kind {
+	case "msg":
+		var msg model.Message
+		if err := json.Unmarshal(entry.Data, &msg); err != nil {
+			return LiveUpdate{}, false
+		}
+		msg.Sequence = entry.Sequence
+		update.Message = &msg
+	case "event":
+		var event model.ConversationEvent
+		if err := json.Unmarshal(entry.Data, &event); err != nil {
+			return LiveUpdate{}, false
+		}
+		event.Sequence = entry.Sequence
+		update.Event = &event
+	default:
+		return LiveUpdate{}, false
+	}
+
+	return update, true
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// pdfInflateRatio bounds decompression. Content streams spend most of their
+// bytes on layout operators, so up to this many stream bytes are inflated per
+// byte of text still wanted, and no more than that for the whole document.
+const pdfInflateRatio = 16
+
+// pdfStream matches a PDF stream object's dictionary and the start of its data.
+var pdfStream = regexp.MustCompile(`(?s)<<((?:[^<>]|<<(?:[^<>]|<[^<>]*>)*>>|<[^<>]*>)*)>>\s*stream\r?\n`)
+
+// extractPDFText returns up to about maxExtractedTextBytes of the text shown
+// by a PDF's content streams. It reads uncompressed and Flate-compressed
+// streams and decodes strings as single-byte text, which covers documents
+// using the standard fonts.
+//
+// Font encodings and ToUnicode maps are not read. Text in embedded fonts with
+// custom encodings comes out garbled, and text in composite (Type0) fonts,
+// such as the Identity-H fonts most tools embed for non-Latin scripts, is
+// glyph IDs rather than characters and is dropped or garbled.
+func extractPDFText(data []byte) string {
+	var text strings.Builder
+	inflateBudget := int64(pdfInflateRatio * maxExtractedTextBytes)
+	for _, loc := range pdfStream.FindAllSubmatchIndex(data, -1) {
+		dict := data[loc[2]:loc[3]]
+		rest := data[loc[1]:]
+		end := bytes.Index(rest, []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		content := rest[:end]
+
+		if bytes.Contains(dict, []byte("/Subtype/Image")) || bytes.Contains(dict, []byte("/Subtype /Image")) {
+			continue
+		}
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			r, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				continue
+			}
+			// Truncated streams still yield what decompressed before the error
+			limit := min(inflateBudget, int64(pdfInflateRatio*(maxExtractedTextBytes-text.Len())))
+			content, _ = io.ReadAll(io.LimitReader(r, limit))
+			inflateBudget -= int64(len(content))
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+
+		showPDFText(&text, content)
+		if text.Len() >= maxExtractedTextBytes || inflateBudget <= 0 {
+			break
+		}
+	}
+	return strings.TrimSpace(text.String())
+}
+
+// showPDFText writes the strings a content stream's text operators show.
+func showPDFText(text *strings.Builder, content []byte) {
+	var operands []string
+	inText := false
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, n := pdfLiteral(content[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			// Dictionaries only carry marked-content properties here
+			i += 2
+		case c == '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return
+			}
+			operands = append(operands, pdfHex(content[i+1:i+end]))
+			i += end + 1
+		case c == '/':
+			// Names are operands
+			for i++; i < len(content) && isPDFRegular(content[i]); i++ {
+			}
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isPDFRegular(c):
+			start := i
+			for i < len(content) && isPDFRegular(content[i]) {
+				i++
+			}
+			switch op := string(content[start:i]); op {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				text.WriteByte('\n')
+			case "Tj", "TJ":
+				if inText {
+					text.WriteString(strings.Join(operands, ""))
+				}
+			case "'", "\"":
+				if inText {
+					text.WriteByte('\n')
+					text.WriteString(strings.Join(operands, ""))
+				}
+			case "T*", "Td", "TD":
+				if inText {
+					text.WriteByte('\n')
+				}
+			default:
+				// Numbers are operands, everything else an operator
+				if !strings.ContainsAny(op[:1], "0123456789.-+") {
+					operands = operands[:0]
+				}
+				continue
+			}
+			operands = operands[:0]
+		default:
+			i++
+		}
+	}
+}
+
+// pdfLiteral decodes the literal string at the start of b and returns it
+// with the number of bytes it spans. Strings that are not printable text,
+// such as the glyph IDs shown with composite fonts, decode as empty.
+func pdfLiteral(b []byte) (string, int) {
+	var s []byte
+	depth := 0
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; c {
+		case '(':
+			if depth > 0 {
+				s = append(s, c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfPrintable(s), i + 1
+			}
+			s = append(s, c)
+		case '\\':
+			i++
+			if i == len(b) {
+				return pdfPrintable(s), i
+			}
+			switch e := b[i]; e {
+			case 'n':
+				s = append(s, '\n')
+			case 'r':
+				s = append(s, '\r')
+			case 't':
+				s = append(s, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					j := i
+					for ; j < len(b) && j < i+3 && b[j] >= '0' && b[j] <= '7'; j++ {
+						v = v*8 + int(b[j]-'0')
+					}
+					s = append(s, byte(v))
+					i = j - 1
+					continue
+				}
+				s = append(s, e)
+			}
+		default:
+			s = append(s, c)
+		}
+	}
+	return pdfPrintable(s), len(b)
+}
+
+// pdfPrintable decodes single-byte text, or returns "" if it holds control
+// characters other than line breaks and tabs.
+func pdfPrintable(b []byte) string {
+	for _, c := range b {
+		if c < 0x20 && !strings.ContainsRune("\t\r\n", rune(c)) {
+			return ""
+		}
+	}
+	return latin1(b)
+}
+
+// latin1 decodes single-byte text, which standard PDF font encodings agree
+// with Latin-1 on for the common characters.
+func latin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// pdfHex decodes a hex string, keeping it only if it is printable text.
+func pdfHex(b []byte) string {
+	digits := bytes.Map(func(r rune) rune {
+		if strings.ContainsRune(" \t\r\n", r) {
+			return -1
+		}
+		return r
+	}, b)
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	decoded, err := hex.DecodeString(string(digits))
+	if err != nil {
+		return ""
+	}
+	for _, c := range decoded {
+		if c < 0x20 && c != '\n' && c != '\t' || c >= 0x7f {
+			return ""
+		}
+	}
+	return string(decoded)
+}
+
+// isPDFRegular reports whether c is a regular character, one that is neither
+// whitespace nor a delimiter.
+func isPDFRegular(c byte) bool {
+	return !strings.ContainsRune(" \t\r\n\f\x00()<>[]{}/%", rune(c))
+}
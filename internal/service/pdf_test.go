@@ -0,0 +1,95 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestExtractPDFText(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{
+			name: "standard font, compressed",
+			file: "testdata/standard_font.pdf",
+			want: "Quarterly report (draft)\n\nRevenue grew 12% year over year.",
+		},
+		{
+			// Known limitation: Identity-H strings are glyph IDs, which are dropped
+			name: "composite font with Identity-H encoding",
+			file: "testdata/identity_h.pdf",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := extractPDFText(data); got != tt.want {
+				t.Errorf("extractPDFText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractPDFTextLimitsInflation(t *testing.T) {
+	// About 256 MiB of path operators deflate to a few hundred KiB
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	chunk := bytes.Repeat([]byte("0 0 m "), 1<<20)
+	for range 256 / 6 {
+		w.Write(chunk)
+	}
+	w.Close()
+
+	var data bytes.Buffer
+	fmt.Fprintf(&data, "%%PDF-1.4\n1 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	data.Write(compressed.Bytes())
+	data.WriteString("\nendstream\nendobj\n%%EOF\n")
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	text := extractPDFText(data.Bytes())
+	runtime.ReadMemStats(&after)
+
+	if text != "" {
+		t.Errorf("extractPDFText() = %q, want no text", text)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 8*pdfInflateRatio*maxExtractedTextBytes {
+		t.Errorf("extractPDFText() allocated %d bytes, want the stream inflated up to the text budget only", allocated)
+	}
+}
+
+func TestPDFLiteral(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		n     int
+	}{
+		{name: "plain", input: "(Hello) Tj", want: "Hello", n: 7},
+		{name: "nested parentheses", input: "(a (b) c)", want: "a (b) c", n: 9},
+		{name: "escapes", input: `(a\)b\nc)`, want: "a)b\nc", n: 9},
+		{name: "octal", input: `(caf\351)`, want: "café", n: 9},
+		{name: "two-byte glyph IDs", input: "(\x00Q\x00u)", want: "", n: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n := pdfLiteral([]byte(tt.input))
+			if got != tt.want || n != tt.n {
+				t.Errorf("pdfLiteral(%q) = %q, %d, want %q, %d", tt.input, got, n, tt.want, tt.n)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"github.com/capitalize-ai/conversational-platform/internal/llm"
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+)
+
+// profileRequest returns a completion request carrying a conversation's
+// generation settings. A nil profile yields the service defaults.
+func profileRequest(profile *model.GenerationProfile) *llm.CompletionRequest {
+	if profile == nil {
+		return &llm.CompletionRequest{}
+	}
+
+	return &llm.CompletionRequest{
+		Model:          profile.Model,
+		System:         profile.SystemPrompt,
+		MaxTokens:      profile.MaxTokens,
+		Temperature:    profile.Temperature,
+		TopP:           profile.TopP,
+		StopSequences:  profile.StopSequences,
+		ThinkingBudget: profile.ThinkingBudget,
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+	natsclient "github.com/capitalize-ai/conversational-platform/internal/nats"
+	"github.com/capitalize-ai/conversational-platform/pkg/logger"
+)
+
+const (
+	// projectorCheckpoint is the checkpoint key used by the conversation projector.
+	projectorCheckpoint = "conversation_index"
+
+	// checkpointInterval is the number of projected messages between checkpoints.
+	checkpointInterval = 500
+)
+
+// ConversationProjector rebuilds the conversation index from the CONVERSATIONS
+// stream. Every write is guarded by Conversation.LastSequence, so replaying a
+// range that was already applied is a no-op.
+type ConversationProjector struct {
+	streamManager *natsclient.StreamManager
+	store         ConversationRepository
+	checkpoints   *natsclient.CheckpointStore
+	logger        *logger.Logger
+}
+
+// NewConversationProjector creates a new conversation projector.
+func NewConversationProjector(
+	streamManager *natsclient.StreamManager,
+	store ConversationRepository,
+	checkpoints *natsclient.CheckpointStore,
+	log *logger.Logger,
+) *ConversationProjector {
+	return &ConversationProjector{
+		streamManager: streamManager,
+		store:         store,
+		checkpoints:   checkpoints,
+		logger:        log,
+	}
+}
+
+// CatchUp projects every message and lifecycle event published since the last
+// checkpoint, then records the new checkpoint.
+func (p *ConversationProjector) CatchUp(ctx context.Context) error {
+	from, err := p.checkpoints.Load(ctx, projectorCheckpoint)
+	if err != nil {
+		return err
+	}
+
+	projected := 0
+	last, err := p.streamManager.Replay(ctx,
+		[]string{natsclient.AllMessagesFilter(), natsclient.AllLifecycleFilter()},
+		from,
+		func(subject string, sequence uint64, data []byte) error {
+			if err := p.apply(ctx, subject, sequence, data); err != nil {
+				return err
+			}
+
+			projected++
+			if projected%checkpointInterval == 0 {
+				return p.checkpoints.Save(ctx, projectorCheckpoint, sequence)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("conversation projection stopped at sequence %d: %w", last, err)
+	}
+
+	if last > from {
+		if err := p.checkpoints.Save(ctx, projectorCheckpoint, last); err != nil {
+			return err
+		}
+	}
+
+	p.logger.Info("conversation index caught up",
+		zap.Uint64("from_sequence", from),
+		zap.Uint64("to_sequence", last),
+		zap.Int("projected", projected),
+	)
+
+	return nil
+}
+
+// apply projects a single stream message into the conversation index.
+func (p *ConversationProjector) apply(ctx context.Context, subject string, sequence uint64, data []byte) error {
+	info, ok := natsclient.ParseSubject(subject)
+	if !ok {
+		return nil
+	}
+
+	switch info.Kind {
+	case "lifecycle":
+		var event model.ConversationLifecycleEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil
+		}
+		return p.upsert(ctx, info.TenantID, info.ConversationID, sequence, func(conv *model.Conversation) {
+			snapshot := event.Conversation
+			conv.UserID = snapshot.UserID
+			conv.Title = snapshot.Title
+			conv.Metadata = snapshot.Metadata
+			conv.Profile = snapshot.Profile
+			conv.Template = snapshot.Template
+			conv.CreatedAt = snapshot.CreatedAt
+			if snapshot.UpdatedAt.After(conv.UpdatedAt) {
+				conv.UpdatedAt = snapshot.UpdatedAt
+			}
+			if event.Action == model.LifecycleDeleted {
+				conv.Deleted = true
+			}
+		})
+
+	case "msg":
+		var msg model.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil
+		}
+		msg.Sequence = sequence
+		return p.upsert(ctx, info.TenantID, info.ConversationID, sequence, func(conv *model.Conversation) {
+			if conv.CreatedAt.IsZero() {
+				conv.CreatedAt = msg.CreatedAt
+			}
+			if msg.CreatedAt.After(conv.UpdatedAt) {
+				conv.UpdatedAt = msg.CreatedAt
+			}
+			// As on the live path, the index never holds reasoning
+			conv.LastMessage = msg.WithoutThinking()
+			conv.MessageCount++
+		})
+	}
+
+	return nil
+}
+
+// upsert applies fn to a conversation unless sequence was already projected,
+// creating the record if the conversation is only known from the stream.
+func (p *ConversationProjector) upsert(ctx context.Context, tenantID, conversationID string, sequence uint64, fn func(*model.Conversation)) error {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		conv, rev, err := p.store.Get(ctx, tenantID, conversationID)
+		switch {
+		case errors.Is(err, natsclient.ErrNotFound):
+			conv = &model.Conversation{ID: conversationID, TenantID: tenantID}
+		case err != nil:
+			return err
+		case sequence <= conv.LastSequence:
+			return nil
+		}
+
+		fn(conv)
+		conv.LastSequence = sequence
+
+		if rev == 0 {
+			_, err = p.store.Create(ctx, conv)
+		} else {
+			_, err = p.store.Update(ctx, conv, rev)
+		}
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, natsclient.ErrRevisionMismatch) {
+			return err
+		}
+	}
+
+	return ErrConversationConflict
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"github.com/capitalize-ai/conversational-platform/internal/llm"
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+)
+
+// ErrInvalidResponseFormat is returned when a response format's schema is not
+// a JSON Schema describing an object.
+var ErrInvalidResponseFormat = errors.New("invalid response format")
+
+const (
+	// maxSchemaAttempts is how many replies a generation with a response
+	// format produces before keeping one that does not match the schema.
+	maxSchemaAttempts = 2
+
+	// maxSchemaErrors caps the violations reported for a reply.
+	maxSchemaErrors = 10
+
+	// schemaURL names a response format's schema while it is compiled. It
+	// never resolves to anything outside the schema itself.
+	schemaURL = "urn:response-format:schema"
+)
+
+// errSchemaLoad is returned for references a response format's schema makes
+// to documents outside itself.
+var errSchemaLoad = errors.New("references outside the schema are not allowed")
+
+// jsonSchema is a compiled JSON Schema. Schemas without a $schema keyword are
+// read as draft 2020-12.
+type jsonSchema struct {
+	schema *jsonschema.Schema
+}
+
+// parseSchema compiles a response format's schema, which must describe an
+// object and may only reference locations within itself.
+func parseSchema(raw json.RawMessage) (*jsonSchema, error) {
+	value, err := decodeJSON(raw)
+	root, ok := value.(map[string]any)
+	if err != nil || !ok {
+		return nil, fmt.Errorf("%w: schema must be a JSON object", ErrInvalidResponseFormat)
+	}
+	if root["type"] != "object" {
+		return nil, fmt.Errorf("%w: schema type must be \"object\"", ErrInvalidResponseFormat)
+	}
+
+	c := jsonschema.NewCompiler()
+	c.DefaultDraft(jsonschema.Draft2020)
+	c.UseLoader(schemaLoader{})
+	if err := c.AddResource(schemaURL, root); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponseFormat, err)
+	}
+	schema, err := c.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponseFormat, err)
+	}
+	return &jsonSchema{schema: schema}, nil
+}
+
+// validate returns the ways a reply fails to be a JSON document matching the
+// schema, or nil if it matches.
+func (s *jsonSchema) validate(content string) []string {
+	value, err := decodeJSON([]byte(content))
+	if err != nil {
+		return []string{"reply is not valid JSON: " + err.Error()}
+	}
+
+	err = s.schema.Validate(value)
+	if err == nil {
+		return nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []string{err.Error()}
+	}
+
+	var violations []string
+	collectViolations(validationErr, &violations)
+	return violations
+}
+
+// collectViolations appends the innermost causes of a validation error, which
+// name the failing location and keyword, up to maxSchemaErrors.
+func collectViolations(err *jsonschema.ValidationError, violations *[]string) {
+	if len(*violations) >= maxSchemaErrors {
+		return
+	}
+	if len(err.Causes) == 0 {
+		*violations = append(*violations, err.Error())
+		return
+	}
+	for _, cause := range err.Causes {
+		collectViolations(cause, violations)
+	}
+}
+
+// schemaLoader refuses every document a schema references by URL, so
+// compiling a schema never reads files or makes requests.
+type schemaLoader struct{}
+
+func (schemaLoader) Load(url string) (any, error) {
+	return nil, errSchemaLoad
+}
+
+// decodeJSON decodes exactly one JSON value, keeping numbers exact.
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON document")
+	}
+	return value, nil
+}
+
+// schemaRetryPrompt asks the model to correct a reply that did not match its
+// response format's schema.
+func schemaRetryPrompt(violations []string) string {
+	return "Your reply did not match the required JSON schema:\n- " +
+		strings.Join(violations, "\n- ") +
+		"\nReply again with only a JSON document that matches the schema."
+}
+
+// llmResponseFormat converts a response format to the LLM's format.
+func llmResponseFormat(format *model.ResponseFormat) *llm.ResponseFormat {
+	return &llm.ResponseFormat{
+		Name:   format.JSONSchema.Name,
+		Schema: format.JSONSchema.Schema,
+	}
+}
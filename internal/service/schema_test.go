@@ -0,0 +1,118 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr bool
+	}{
+		{name: "object", schema: `{"type": "object", "properties": {"name": {"type": "string"}}}`},
+		{name: "local reference", schema: `{"type": "object", "$defs": {"id": {"type": "integer"}}, "properties": {"id": {"$ref": "#/$defs/id"}}}`},
+		{name: "not an object", schema: `[]`, wantErr: true},
+		{name: "not valid JSON", schema: `{"type": `, wantErr: true},
+		{name: "array type", schema: `{"type": "array"}`, wantErr: true},
+		{name: "invalid pattern", schema: `{"type": "object", "properties": {"code": {"type": "string", "pattern": "("}}}`, wantErr: true},
+		{name: "invalid keyword value", schema: `{"type": "object", "required": "name"}`, wantErr: true},
+		{name: "unresolvable reference", schema: `{"type": "object", "properties": {"id": {"$ref": "#/$defs/missing"}}}`, wantErr: true},
+		{name: "external reference", schema: `{"type": "object", "properties": {"id": {"$ref": "https://example.com/id.json"}}}`, wantErr: true},
+		{name: "file reference", schema: `{"type": "object", "properties": {"id": {"$ref": "file:///etc/passwd"}}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSchema(json.RawMessage(tt.schema))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidResponseFormat) {
+					t.Errorf("parseSchema() error = %v, want %v", err, ErrInvalidResponseFormat)
+				}
+			} else if err != nil {
+				t.Errorf("parseSchema() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := parseSchema(json.RawMessage(`{
+		"type": "object",
+		"$defs": {
+			"contact": {
+				"type": "object",
+				"properties": {"email": {"type": "string", "pattern": "^[^@]+@[^@]+$"}},
+				"required": ["email"]
+			}
+		},
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"status": {"enum": ["open", "closed"]},
+			"count": {"type": "integer", "minimum": 0},
+			"contact": {"$ref": "#/$defs/contact"},
+			"id": {"anyOf": [{"type": "string"}, {"type": "integer"}]}
+		},
+		"required": ["name"],
+		"additionalProperties": false
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string // substrings of the expected violations, in order
+	}{
+		{name: "valid", content: `{"name": "a", "status": "open", "count": 3, "contact": {"email": "a@b.c"}, "id": 7}`},
+		{name: "missing required property", content: `{}`, want: []string{"name"}},
+		{name: "wrong type", content: `{"name": "a", "count": "three"}`, want: []string{"/count"}},
+		{name: "integer written as a float", content: `{"name": "a", "count": 1.0}`},
+		{name: "not an allowed value", content: `{"name": "a", "status": "pending"}`, want: []string{"/status"}},
+		{name: "pattern through a reference", content: `{"name": "a", "contact": {"email": "nobody"}}`, want: []string{"/contact/email"}},
+		{name: "matches no alternative", content: `{"name": "a", "id": true}`, want: []string{"/id", "/id"}},
+		{name: "additional property", content: `{"name": "a", "extra": 1}`, want: []string{"extra"}},
+		{name: "not valid JSON", content: `{"name": `, want: []string{"reply is not valid JSON"}},
+		{name: "trailing data", content: `{"name": "a"} {}`, want: []string{"reply is not valid JSON"}},
+		{name: "not an object", content: `"a"`, want: []string{"object"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schema.validate(tt.content)
+			if len(got) != len(tt.want) {
+				t.Fatalf("validate() = %q, want %d violations", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("validate()[%d] = %q, want it to mention %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaValidateCapsViolations(t *testing.T) {
+	schema, err := parseSchema(json.RawMessage(`{"type": "object", "additionalProperties": {"type": "string"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var content strings.Builder
+	content.WriteString("{")
+	for i := range 2 * maxSchemaErrors {
+		if i > 0 {
+			content.WriteString(",")
+		}
+		content.WriteString(`"k` + strings.Repeat("x", i) + `": 1`)
+	}
+	content.WriteString("}")
+
+	if got := schema.validate(content.String()); len(got) != maxSchemaErrors {
+		t.Errorf("validate() returned %d violations, want %d", len(got), maxSchemaErrors)
+	}
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/capitalize-ai/conversational-platform/internal/llm"
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+	natsclient "github.com/capitalize-ai/conversational-platform/internal/nats"
+	"github.com/capitalize-ai/conversational-platform/pkg/logger"
+)
+
+// summaryMaxTokens caps the length of a generated summary.
+const summaryMaxTokens = 1024
+
+// SummarizerConfig configures a Summarizer.
+type SummarizerConfig struct {
+	// Threshold is the estimated token count of unsummarized history that
+	// triggers a new summary. Zero disables summarization.
+	Threshold int
+
+	// KeepRecent is the estimated token count of the newest turns left out of
+	// a summary, so they still reach the model verbatim.
+	KeepRecent int
+}
+
+// Summarizer keeps a rolling summary of long conversations so early context
+// survives once it no longer fits in the model's context window. Each summary
+// folds the previous one into the messages that followed it.
+type Summarizer struct {
+	streamManager *natsclient.StreamManager
+	llmClient     llm.Client
+	config        SummarizerConfig
+	logger        *logger.Logger
+}
+
+// NewSummarizer creates a new summarizer.
+func NewSummarizer(
+	streamManager *natsclient.StreamManager,
+	llmClient llm.Client,
+	config SummarizerConfig,
+	log *logger.Logger,
+) *Summarizer {
+	return &Summarizer{
+		streamManager: streamManager,
+		llmClient:     llmClient,
+		config:        config,
+		logger:        log,
+	}
+}
+
+// Latest returns the newest summary that covers nothing after throughSequence,
+// or nil if there is none.
+func (s *Summarizer) Latest(ctx context.Context, tenantID, conversationID string, throughSequence uint64) (*model.Summary, error) {
+	summary, err := s.streamManager.LatestSummary(ctx, tenantID, conversationID)
+	if err != nil {
+		if errors.Is(err, natsclient.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if throughSequence > 0 && summary.ThroughSequence > throughSequence {
+		return nil, nil
+	}
+
+	return summary, nil
+}
+
+// Update summarizes a conversation's history up to throughSequence once the
+// part not yet covered by a summary exceeds the configured threshold.
+func (s *Summarizer) Update(ctx context.Context, tenantID, conversationID string, throughSequence uint64, modelName string) error {
+	if s.llmClient == nil || s.config.Threshold <= 0 {
+		return nil
+	}
+
+	previous, err := s.Latest(ctx, tenantID, conversationID, throughSequence)
+	if err != nil {
+		return err
+	}
+
+	var afterSequence uint64
+	if previous != nil {
+		afterSequence = previous.ThroughSequence
+	}
+
+	var pending []model.Message
+	total := 0
+	_, err = s.streamManager.Replay(ctx, []string{natsclient.MessageFilter(tenantID, conversationID)}, afterSequence, func(_ string, sequence uint64, data []byte) error {
+		if sequence > throughSequence {
+			return nil
+		}
+
+		var msg model.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil
+		}
+		// System prompts are always sent in full
+		if msg.Role == model.RoleSystem {
+			return nil
+		}
+
+		msg.Sequence = sequence
+		pending = append(pending, msg)
+		total += estimateMessageTokens(modelName, &msg)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read unsummarized history: %w", err)
+	}
+
+	if total < s.config.Threshold {
+		return nil
+	}
+
+	// Leave the newest turns out of the summary
+	cut := len(pending)
+	for kept := 0; cut > 0; cut-- {
+		kept += estimateMessageTokens(modelName, &pending[cut-1])
+		if kept > s.config.KeepRecent {
+			break
+		}
+	}
+	if cut == 0 {
+		return nil
+	}
+
+	// Fold the history into the summary in chunks that fit the model
+	content := ""
+	if previous != nil {
+		content = previous.Content
+	}
+	chunkBudget := llm.ContextWindow(modelName)/2 - summaryMaxTokens
+	for start := 0; start < cut; {
+		end, used := start, 0
+		for end < cut {
+			cost := estimateMessageTokens(modelName, &pending[end])
+			if used+cost > chunkBudget && end > start {
+				break
+			}
+			used += cost
+			end++
+		}
+
+		content, err = s.summarize(ctx, modelName, content, pending[start:end])
+		if err != nil {
+			return err
+		}
+		start = end
+	}
+
+	fromSequence := pending[0].Sequence
+	if previous != nil {
+		fromSequence = previous.FromSequence
+	}
+
+	summary := &model.Summary{
+		Message: model.Message{
+			ID:             uuid.Must(uuid.NewV7()).String(),
+			ConversationID: conversationID,
+			TenantID:       tenantID,
+			Role:           model.RoleSystem,
+			Content:        content,
+			Model:          &modelName,
+			CreatedAt:      time.Now(),
+		},
+		FromSequence:    fromSequence,
+		ThroughSequence: pending[cut-1].Sequence,
+	}
+
+	if _, err := s.streamManager.PublishSummary(ctx, summary); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// summarize asks the LLM to extend a summary with the given messages.
+func (s *Summarizer) summarize(ctx context.Context, modelName, previous string, messages []model.Message) (string, error) {
+	var prompt strings.Builder
+	prompt.WriteString("Summarize the conversation below for an assistant that will continue it. ")
+	prompt.WriteString("Keep facts, decisions, open questions and user preferences; drop pleasantries. ")
+	prompt.WriteString("Reply with the summary only.\n\n")
+
+	if previous != "" {
+		prompt.WriteString("Summary of the conversation so far:\n")
+		prompt.WriteString(previous)
+		prompt.WriteString("\n\nMessages since then:\n")
+	}
+
+	for _, msg := range messages {
+		fmt.Fprintf(&prompt, "%s: %s\n", msg.Role, msg.Content)
+		for _, block := range msg.ContentBlocks {
+			switch block.Type {
+			case model.ContentBlockImage:
+				fmt.Fprintf(&prompt, "%s attached an image\n", msg.Role)
+			case model.ContentBlockAttachment:
+				fmt.Fprintf(&prompt, "%s attached %q\n", msg.Role, block.Name)
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			fmt.Fprintf(&prompt, "%s called %s with %s\n", msg.Role, call.Name, call.Arguments)
+		}
+	}
+
+	resp, err := s.llmClient.Complete(ctx, &llm.CompletionRequest{
+		Model: modelName,
+		Messages: []llm.ChatMessage{
+			{Role: string(model.RoleUser), Content: prompt.String()},
+		},
+		MaxTokens: summaryMaxTokens,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+
+	return resp.Content, nil
+}
+
+// estimateMessageTokens approximates the context cost of a message sent to a model.
+func estimateMessageTokens(modelName string, msg *model.Message) int {
+	return llm.EstimateTokens(modelName, chatMessage(msg))
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+	natsclient "github.com/capitalize-ai/conversational-platform/internal/nats"
+	"github.com/capitalize-ai/conversational-platform/pkg/logger"
+)
+
+var (
+	// ErrTemplateNotFound is returned when a template or template version does not exist for the tenant.
+	ErrTemplateNotFound = errors.New("template not found")
+
+	// ErrTemplateConflict is returned when concurrent writers keep racing on a template.
+	ErrTemplateConflict = errors.New("template was modified concurrently")
+
+	// ErrInvalidTemplate is returned when a template does not parse or cannot be rendered.
+	ErrInvalidTemplate = errors.New("invalid template")
+)
+
+// TemplateRepository persists prompt templates and their versions with
+// revision-based optimistic concurrency.
+type TemplateRepository interface {
+	// Create stores a new template and returns its revision.
+	Create(ctx context.Context, tpl *model.PromptTemplate) (uint64, error)
+
+	// Get returns the current version of a template and its revision.
+	Get(ctx context.Context, tenantID, templateID string) (*model.PromptTemplate, uint64, error)
+
+	// GetVersion returns a specific version of a template.
+	GetVersion(ctx context.Context, tenantID, templateID string, version int) (*model.PromptTemplate, error)
+
+	// List returns the current version of all templates stored for a tenant.
+	List(ctx context.Context, tenantID string) ([]model.PromptTemplate, error)
+
+	// Update writes a template if its stored revision still matches.
+	Update(ctx context.Context, tpl *model.PromptTemplate, revision uint64) (uint64, error)
+}
+
+// TemplateService handles prompt template operations.
+type TemplateService struct {
+	store  TemplateRepository
+	logger *logger.Logger
+}
+
+// NewTemplateService creates a new template service.
+func NewTemplateService(store TemplateRepository, log *logger.Logger) *TemplateService {
+	return &TemplateService{
+		store:  store,
+		logger: log,
+	}
+}
+
+// Create creates a new prompt template at version 1.
+func (s *TemplateService) Create(ctx context.Context, tenantID string, req *model.CreateTemplateRequest) (*model.PromptTemplate, error) {
+	if _, err := parseTemplate(req.Content); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	tpl := &model.PromptTemplate{
+		ID:          uuid.Must(uuid.NewV7()).String(),
+		TenantID:    tenantID,
+		Name:        req.Name,
+		Description: req.Description,
+		Content:     req.Content,
+		Version:     1,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if _, err := s.store.Create(ctx, tpl); err != nil {
+		return nil, fmt.Errorf("failed to store template: %w", err)
+	}
+
+	s.logger.Info("template created",
+		zap.String("template_id", tpl.ID),
+		zap.String("tenant_id", tenantID),
+	)
+
+	return tpl, nil
+}
+
+// Get retrieves a template. A zero version selects the current one.
+func (s *TemplateService) Get(ctx context.Context, tenantID, templateID string, version int) (*model.PromptTemplate, error) {
+	current, _, err := s.get(ctx, tenantID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == 0 || version == current.Version {
+		return current, nil
+	}
+
+	tpl, err := s.store.GetVersion(ctx, tenantID, templateID, version)
+	if err != nil {
+		if errors.Is(err, natsclient.ErrNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+
+	return tpl, nil
+}
+
+// List retrieves a tenant's templates, sorted by name.
+func (s *TemplateService) List(ctx context.Context, tenantID string) (*model.ListTemplatesResponse, error) {
+	stored, err := s.store.List(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	templates := make([]model.PromptTemplate, 0, len(stored))
+	for _, tpl := range stored {
+		if !tpl.Deleted {
+			templates = append(templates, tpl)
+		}
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+
+	return &model.ListTemplatesResponse{
+		Templates: templates,
+		Total:     len(templates),
+	}, nil
+}
+
+// Update updates a template. Changing its content creates a new version.
+func (s *TemplateService) Update(ctx context.Context, tenantID, templateID string, req *model.UpdateTemplateRequest) (*model.PromptTemplate, error) {
+	if req.Content != "" {
+		if _, err := parseTemplate(req.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.mutate(ctx, tenantID, templateID, func(tpl *model.PromptTemplate) {
+		if req.Name != "" {
+			tpl.Name = req.Name
+		}
+		if req.Description != "" {
+			tpl.Description = req.Description
+		}
+		if req.Content != "" && req.Content != tpl.Content {
+			tpl.Content = req.Content
+			tpl.Version++
+		}
+		tpl.UpdatedAt = time.Now()
+	})
+}
+
+// Delete soft deletes a template. Conversations created from it keep their
+// rendered prompt.
+func (s *TemplateService) Delete(ctx context.Context, tenantID, templateID string) error {
+	_, err := s.mutate(ctx, tenantID, templateID, func(tpl *model.PromptTemplate) {
+		tpl.Deleted = true
+		tpl.UpdatedAt = time.Now()
+	})
+	return err
+}
+
+// Render renders the referenced template version with its variables. It
+// returns the prompt along with the reference pinned to the version used.
+func (s *TemplateService) Render(ctx context.Context, tenantID string, ref *model.TemplateRef) (string, *model.TemplateRef, error) {
+	tpl, err := s.Get(ctx, tenantID, ref.ID, ref.Version)
+	if err != nil {
+		return "", nil, err
+	}
+
+	parsed, err := parseTemplate(tpl.Content)
+	if err != nil {
+		return "", nil, err
+	}
+
+	vars := ref.Variables
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	var out strings.Builder
+	if err := parsed.Execute(&out, vars); err != nil {
+		return "", nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+
+	return out.String(), &model.TemplateRef{
+		ID:        tpl.ID,
+		Version:   tpl.Version,
+		Variables: ref.Variables,
+	}, nil
+}
+
+// parseTemplate parses template content. Rendering fails on variables that
+// were not supplied rather than silently leaving them blank.
+func parseTemplate(content string) (*template.Template, error) {
+	tpl, err := template.New("prompt").Option("missingkey=error").Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+	return tpl, nil
+}
+
+// get loads a live template and its revision, hiding other tenants' and deleted templates.
+func (s *TemplateService) get(ctx context.Context, tenantID, templateID string) (*model.PromptTemplate, uint64, error) {
+	tpl, rev, err := s.store.Get(ctx, tenantID, templateID)
+	if err != nil {
+		if errors.Is(err, natsclient.ErrNotFound) {
+			return nil, 0, ErrTemplateNotFound
+		}
+		return nil, 0, err
+	}
+
+	if tpl.TenantID != tenantID || tpl.Deleted {
+		return nil, 0, ErrTemplateNotFound
+	}
+
+	return tpl, rev, nil
+}
+
+// mutate applies fn to the current template and writes it back, retrying when
+// another writer got there first.
+func (s *TemplateService) mutate(ctx context.Context, tenantID, templateID string, fn func(*model.PromptTemplate)) (*model.PromptTemplate, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		tpl, rev, err := s.get(ctx, tenantID, templateID)
+		if err != nil {
+			return nil, err
+		}
+
+		fn(tpl)
+
+		_, err = s.store.Update(ctx, tpl, rev)
+		if err == nil {
+			return tpl, nil
+		}
+		if !errors.Is(err, natsclient.ErrRevisionMismatch) {
+			return nil, fmt.Errorf("failed to update template: %w", err)
+		}
+	}
+
+	return nil, ErrTemplateConflict
+}
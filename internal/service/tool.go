@@ -0,0 +1,392 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sort"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/capitalize-ai/conversational-platform/internal/llm"
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+	natsclient "github.com/capitalize-ai/conversational-platform/internal/nats"
+	"github.com/capitalize-ai/conversational-platform/pkg/logger"
+)
+
+var (
+	// ErrToolNotFound is returned when a tool does not exist for the tenant.
+	ErrToolNotFound = errors.New("tool not found")
+
+	// ErrToolExists is returned when a tenant already has a tool with the name.
+	ErrToolExists = errors.New("tool already exists")
+
+	// ErrToolConflict is returned when concurrent writers keep racing on a tool.
+	ErrToolConflict = errors.New("tool was modified concurrently")
+
+	// ErrInvalidTool is returned when a tool's input schema is not a JSON Schema object.
+	ErrInvalidTool = errors.New("invalid tool")
+
+	// errWebhookNotHTTPS is returned for webhook URLs and redirects not using https.
+	errWebhookNotHTTPS = errors.New("webhook must use https")
+
+	// errWebhookAddress is returned when a webhook resolves to a non-public address.
+	errWebhookAddress = errors.New("webhook address is not public")
+)
+
+// maxToolResultBytes caps the webhook response returned to the LLM.
+const maxToolResultBytes = 64 * 1024
+
+// maxWebhookRedirects caps the redirects followed for a webhook call.
+const maxWebhookRedirects = 5
+
+// reservedPrefixes are ranges that are neither private nor link-local by the
+// standard library's reckoning but still reach internal infrastructure.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT, used by some cloud metadata services
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64, which can embed any IPv4 address
+}
+
+// ToolRepository persists tool definitions with revision-based optimistic
+// concurrency.
+type ToolRepository interface {
+	// Create stores a new tool and returns its revision.
+	Create(ctx context.Context, tool *model.Tool) (uint64, error)
+
+	// Get returns a tool and its revision.
+	Get(ctx context.Context, tenantID, name string) (*model.Tool, uint64, error)
+
+	// List returns all tools stored for a tenant.
+	List(ctx context.Context, tenantID string) ([]model.Tool, error)
+
+	// Update writes a tool if its stored revision still matches.
+	Update(ctx context.Context, tool *model.Tool, revision uint64) (uint64, error)
+
+	// Delete removes a tool.
+	Delete(ctx context.Context, tenantID, name string) error
+}
+
+// ToolConfig configures tool execution.
+type ToolConfig struct {
+	// Timeout bounds each webhook call.
+	Timeout time.Duration
+
+	// MaxIterations is the number of tool-calling rounds a generation may run
+	// before the model's reply is accepted as final.
+	MaxIterations int
+}
+
+// ToolService handles tool definitions and executes tool calls against their
+// webhooks.
+type ToolService struct {
+	store      ToolRepository
+	config     ToolConfig
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+// NewToolService creates a new tool service.
+func NewToolService(store ToolRepository, config ToolConfig, log *logger.Logger) *ToolService {
+	return &ToolService{
+		store:      store,
+		config:     config,
+		httpClient: newWebhookClient(config.Timeout),
+		logger:     log,
+	}
+}
+
+// newWebhookClient returns the HTTP client tool webhooks are called with.
+// Webhook URLs are tenant-supplied, so it connects only to public addresses,
+// checked after DNS resolution so no hostname can point it inward, and
+// follows redirects only to https URLs, which are dialled the same way.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: dialPublicOnly,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialled in place of the webhook and defeat the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxWebhookRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxWebhookRedirects)
+			}
+			return requireHTTPS(req.URL)
+		},
+	}
+}
+
+// dialPublicOnly refuses connections to loopback, private, link-local (such
+// as the 169.254.169.254 metadata endpoint) and other internal addresses.
+func dialPublicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errWebhookAddress, host)
+	}
+	ip = ip.Unmap()
+
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return fmt.Errorf("%w: %s", errWebhookAddress, ip)
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("%w: %s", errWebhookAddress, ip)
+		}
+	}
+
+	return nil
+}
+
+// requireHTTPS checks that a webhook URL, or a redirect from one, uses https.
+func requireHTTPS(u *url.URL) error {
+	if u.Scheme != "https" {
+		return errWebhookNotHTTPS
+	}
+	return nil
+}
+
+// Create registers a tool for a tenant.
+func (s *ToolService) Create(ctx context.Context, tenantID string, req *model.CreateToolRequest) (*model.Tool, error) {
+	if err := validateInputSchema(req.InputSchema); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	tool := &model.Tool{
+		TenantID:    tenantID,
+		Name:        req.Name,
+		Description: req.Description,
+		InputSchema: req.InputSchema,
+		WebhookURL:  req.WebhookURL,
+		Secret:      req.Secret,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if _, err := s.store.Create(ctx, tool); err != nil {
+		if errors.Is(err, natsclient.ErrRevisionMismatch) {
+			return nil, ErrToolExists
+		}
+		return nil, fmt.Errorf("failed to store tool: %w", err)
+	}
+
+	s.logger.Info("tool created",
+		zap.String("tool", tool.Name),
+		zap.String("tenant_id", tenantID),
+	)
+
+	return tool, nil
+}
+
+// Get retrieves a tool.
+func (s *ToolService) Get(ctx context.Context, tenantID, name string) (*model.Tool, error) {
+	tool, _, err := s.get(ctx, tenantID, name)
+	return tool, err
+}
+
+// List retrieves a tenant's tools, sorted by name.
+func (s *ToolService) List(ctx context.Context, tenantID string) (*model.ListToolsResponse, error) {
+	tools, err := s.store.List(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+	if tools == nil {
+		tools = []model.Tool{}
+	}
+
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name < tools[j].Name
+	})
+
+	return &model.ListToolsResponse{
+		Tools: tools,
+		Total: len(tools),
+	}, nil
+}
+
+// Update updates a tool.
+func (s *ToolService) Update(ctx context.Context, tenantID, name string, req *model.UpdateToolRequest) (*model.Tool, error) {
+	if len(req.InputSchema) > 0 {
+		if err := validateInputSchema(req.InputSchema); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		tool, rev, err := s.get(ctx, tenantID, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if req.Description != "" {
+			tool.Description = req.Description
+		}
+		if len(req.InputSchema) > 0 {
+			tool.InputSchema = req.InputSchema
+		}
+		if req.WebhookURL != "" {
+			tool.WebhookURL = req.WebhookURL
+		}
+		if req.Secret != "" {
+			tool.Secret = req.Secret
+		}
+		tool.UpdatedAt = time.Now()
+
+		_, err = s.store.Update(ctx, tool, rev)
+		if err == nil {
+			return tool, nil
+		}
+		if !errors.Is(err, natsclient.ErrRevisionMismatch) {
+			return nil, fmt.Errorf("failed to update tool: %w", err)
+		}
+	}
+
+	return nil, ErrToolConflict
+}
+
+// Delete removes a tool. Messages recording earlier calls to it are kept.
+func (s *ToolService) Delete(ctx context.Context, tenantID, name string) error {
+	if _, _, err := s.get(ctx, tenantID, name); err != nil {
+		return err
+	}
+	return s.store.Delete(ctx, tenantID, name)
+}
+
+// Definitions returns the tools a tenant's generations may call.
+func (s *ToolService) Definitions(ctx context.Context, tenantID string) ([]llm.Tool, error) {
+	resp, err := s.List(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := make([]llm.Tool, 0, len(resp.Tools))
+	for _, tool := range resp.Tools {
+		tools = append(tools, llm.Tool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema,
+		})
+	}
+
+	return tools, nil
+}
+
+// Execute runs a tool call by POSTing it to the tool's webhook and returns
+// the result to show the model. Failures are returned as results flagged
+// isError rather than as errors, so the model can recover from them.
+func (s *ToolService) Execute(ctx context.Context, tenantID, conversationID string, call llm.ToolCall) (result string, isError bool) {
+	tool, _, err := s.get(ctx, tenantID, call.Name)
+	if err != nil {
+		return fmt.Sprintf("tool %q is not available", call.Name), true
+	}
+
+	body, err := json.Marshal(&model.ToolWebhookRequest{
+		TenantID:       tenantID,
+		ConversationID: conversationID,
+		CallID:         call.ID,
+		Tool:           call.Name,
+		Arguments:      call.Arguments,
+	})
+	if err != nil {
+		return "invalid tool arguments", true
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tool.WebhookURL, bytes.NewReader(body))
+	if err != nil || requireHTTPS(req.URL) != nil {
+		return "tool webhook is misconfigured", true
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// A redelivered generation may call again; webhooks dedupe on the call ID
+	req.Header.Set("Idempotency-Key", call.ID)
+	if tool.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(tool.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.logger.Warn("tool webhook failed",
+			zap.String("tool", call.Name),
+			zap.String("conversation_id", conversationID),
+			zap.Error(err),
+		)
+		if errors.Is(err, errWebhookAddress) || errors.Is(err, errWebhookNotHTTPS) {
+			return "tool call failed: webhook address not allowed", true
+		}
+		return "tool call failed: webhook unreachable", true
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxToolResultBytes+1))
+	if err != nil {
+		return "tool call failed: could not read webhook response", true
+	}
+	if len(data) > maxToolResultBytes {
+		return "tool call failed: result too large", true
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Sprintf("tool call failed with status %d: %s", resp.StatusCode, data), true
+	}
+
+	return string(data), false
+}
+
+// validateInputSchema checks that a tool's input schema describes an object,
+// which is what both providers accept.
+func validateInputSchema(schema json.RawMessage) error {
+	var parsed struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(schema, &parsed); err != nil {
+		return fmt.Errorf("%w: input_schema must be a JSON object", ErrInvalidTool)
+	}
+	if parsed.Type != "object" {
+		return fmt.Errorf("%w: input_schema type must be \"object\"", ErrInvalidTool)
+	}
+	return nil
+}
+
+// get loads a tool and its revision, hiding other tenants' tools.
+func (s *ToolService) get(ctx context.Context, tenantID, name string) (*model.Tool, uint64, error) {
+	tool, rev, err := s.store.Get(ctx, tenantID, name)
+	if err != nil {
+		if errors.Is(err, natsclient.ErrNotFound) {
+			return nil, 0, ErrToolNotFound
+		}
+		return nil, 0, err
+	}
+
+	if tool.TenantID != tenantID {
+		return nil, 0, ErrToolNotFound
+	}
+
+	return tool, rev, nil
+}
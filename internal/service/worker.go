@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/capitalize-ai/conversational-platform/internal/model"
+	natsclient "github.com/capitalize-ai/conversational-platform/internal/nats"
+	"github.com/capitalize-ai/conversational-platform/pkg/logger"
+	"github.com/capitalize-ai/conversational-platform/pkg/metrics"
+)
+
+const (
+	// workerConsumer is the durable consumer name shared by all generation workers.
+	workerConsumer = "generation-workers"
+
+	// jobAckWait is how long a job may go without a progress signal before it
+	// is redelivered, e.g. because the worker running it crashed.
+	jobAckWait = 30 * time.Second
+
+	// jobMaxDeliver bounds delivery attempts per job.
+	jobMaxDeliver = 3
+
+	// jobRetryDelay is the backoff before a failed job is redelivered.
+	jobRetryDelay = 5 * time.Second
+)
+
+// WorkerConfig configures a GenerationWorker.
+type WorkerConfig struct {
+	// Concurrency is the maximum number of generations run at once.
+	Concurrency int
+
+	// Timeout bounds a single generation, and the summary update after it.
+	Timeout time.Duration
+
+	// SummaryConcurrency is the maximum number of summary updates run at
+	// once, outside the generation slots.
+	SummaryConcurrency int
+}
+
+// GenerationWorker runs queued generation jobs with bounded concurrency,
+// independently of the HTTP request that queued them. Jobs are acked only
+// after the assistant message is persisted, so a crashed worker's jobs are
+// redelivered to another replica.
+type GenerationWorker struct {
+	streamManager  *natsclient.StreamManager
+	messageService *MessageService
+	config         WorkerConfig
+	logger         *logger.Logger
+
+	slots    chan struct{}
+	inflight sync.WaitGroup
+	stop     func()
+
+	summaries   chan struct{}
+	summarizing sync.WaitGroup
+
+	// jobsCtx is the parent of every generation; cancelling it aborts them.
+	jobsCtx    context.Context
+	cancelJobs context.CancelFunc
+}
+
+// NewGenerationWorker creates a new generation worker.
+func NewGenerationWorker(
+	streamManager *natsclient.StreamManager,
+	messageService *MessageService,
+	cfg WorkerConfig,
+	log *logger.Logger,
+) *GenerationWorker {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.SummaryConcurrency <= 0 {
+		cfg.SummaryConcurrency = 1
+	}
+
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+
+	return &GenerationWorker{
+		streamManager:  streamManager,
+		messageService: messageService,
+		config:         cfg,
+		logger:         log,
+		slots:          make(chan struct{}, cfg.Concurrency),
+		summaries:      make(chan struct{}, cfg.SummaryConcurrency),
+		jobsCtx:        jobsCtx,
+		cancelJobs:     cancelJobs,
+	}
+}
+
+// Start begins consuming generation jobs.
+func (w *GenerationWorker) Start(ctx context.Context) error {
+	stop, err := w.streamManager.ConsumeJobs(ctx, natsclient.JobConsumerConfig{
+		Durable:     workerConsumer,
+		AckWait:     jobAckWait,
+		MaxDeliver:  jobMaxDeliver,
+		MaxBuffered: w.config.Concurrency,
+	}, w.dispatch)
+	if err != nil {
+		return err
+	}
+
+	w.stop = stop
+	return nil
+}
+
+// Stop stops taking new jobs and waits for in-flight generations and summary
+// updates to finish. Generations still running when ctx expires are aborted
+// and left unacked so another replica picks them up.
+func (w *GenerationWorker) Stop(ctx context.Context) {
+	if w.stop != nil {
+		w.stop()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		// Generations start summaries, so wait for them first
+		w.inflight.Wait()
+		w.summarizing.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		w.cancelJobs()
+		<-done
+	}
+}
+
+// dispatch blocks until a slot is free, then runs the job in the background.
+// Blocking here is what bounds the number of jobs pulled from the consumer.
+func (w *GenerationWorker) dispatch(delivery *natsclient.JobDelivery) {
+	w.slots <- struct{}{}
+	w.inflight.Add(1)
+
+	go func() {
+		defer func() {
+			<-w.slots
+			w.inflight.Done()
+		}()
+		w.run(delivery)
+	}()
+}
+
+// run executes one job, keeping it alive on the consumer while it runs.
+func (w *GenerationWorker) run(delivery *natsclient.JobDelivery) {
+	job := &delivery.Job
+	job.Attempt = int(delivery.NumDelivered())
+
+	ctx, cancel := context.WithTimeout(w.jobsCtx, w.config.Timeout)
+	defer cancel()
+
+	metrics.GenerationJobsActive.Inc()
+	defer metrics.GenerationJobsActive.Dec()
+
+	// Signal progress well inside the ack wait while the generation runs
+	heartbeat := time.NewTicker(jobAckWait / 3)
+	defer heartbeat.Stop()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-heartbeat.C:
+				delivery.InProgress()
+			}
+		}
+	}()
+
+	// A job cancelled while queued is dropped; if the check fails, generate anyway
+	if cancelled, err := w.messageService.Cancelled(ctx, job); err != nil {
+		w.logger.Warn("failed to check job cancellation", zap.String("job_id", job.ID), zap.Error(err))
+	} else if cancelled {
+		delivery.Ack()
+		metrics.GenerationJobsTotal.WithLabelValues("cancelled").Inc()
+		return
+	}
+
+	reply, err := w.messageService.Generate(ctx, job)
+	if err == nil {
+		delivery.Ack()
+		metrics.GenerationJobsTotal.WithLabelValues("success").Inc()
+
+		w.summarize(job, reply)
+		return
+	}
+
+	log := w.logger.With(
+		zap.String("job_id", job.ID),
+		zap.String("conversation_id", job.ConversationID),
+		zap.Uint64("attempt", delivery.NumDelivered()),
+		zap.Error(err),
+	)
+
+	switch {
+	case w.jobsCtx.Err() != nil:
+		// Shutting down: leave the job for another replica
+		log.Info("generation job interrupted by shutdown")
+		delivery.Nak(0)
+		metrics.GenerationJobsTotal.WithLabelValues("interrupted").Inc()
+
+	case isTerminalGenerationError(err) || delivery.NumDelivered() >= jobMaxDeliver:
+		log.Error("generation job failed")
+		w.messageService.PublishGenerationError(context.Background(), job, err)
+		delivery.Term(err.Error())
+		metrics.GenerationJobsTotal.WithLabelValues("failed").Inc()
+
+	default:
+		log.Warn("generation job failed, will retry")
+		delivery.Nak(jobRetryDelay)
+		metrics.GenerationJobsTotal.WithLabelValues("retried").Inc()
+	}
+}
+
+// summarize updates the conversation summary in the background, so the job's
+// slot is free for the next generation meanwhile. When SummaryConcurrency
+// updates are already running the update is skipped; the next turn's update
+// covers what it would have.
+func (w *GenerationWorker) summarize(job *model.GenerationJob, reply *model.Message) {
+	select {
+	case w.summaries <- struct{}{}:
+	default:
+		w.logger.Debug("summary updates at capacity, leaving this one to the next turn",
+			zap.String("conversation_id", job.ConversationID),
+		)
+		return
+	}
+	w.summarizing.Add(1)
+
+	go func() {
+		defer func() {
+			<-w.summaries
+			w.summarizing.Done()
+		}()
+
+		ctx, cancel := context.WithTimeout(w.jobsCtx, w.config.Timeout)
+		defer cancel()
+		w.messageService.Summarize(ctx, job, reply)
+	}()
+}
+
+// isTerminalGenerationError reports whether retrying a job cannot help.
+func isTerminalGenerationError(err error) bool {
+	return errors.Is(err, ErrLLMNotConfigured) || errors.Is(err, ErrLLMStreamFailed)
+}
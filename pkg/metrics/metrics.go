@@ -45,6 +45,25 @@ var (
 		[]string{"model", "direction"},
 	)
 
+	// LLMCircuitState tracks the circuit breaker state per provider and model
+	// (0 closed, 1 half-open, 2 open).
+	LLMCircuitState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "llm_circuit_state",
+			Help: "LLM circuit breaker state (0 closed, 1 half-open, 2 open)",
+		},
+		[]string{"provider", "model"},
+	)
+
+	// LLMCircuitTransitions tracks circuit breaker state changes.
+	LLMCircuitTransitions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "llm_circuit_transitions_total",
+			Help: "Total LLM circuit breaker state changes",
+		},
+		[]string{"provider", "model", "state"},
+	)
+
 	// SSEConnectionsActive tracks active SSE connections.
 	SSEConnectionsActive = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -89,6 +108,23 @@ var (
 		[]string{"tenant_id"},
 	)
 
+	// GenerationJobsActive tracks generation jobs currently running on this replica.
+	GenerationJobsActive = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "generation_jobs_active",
+			Help: "Number of generation jobs currently running",
+		},
+	)
+
+	// GenerationJobsTotal tracks finished generation job attempts by outcome.
+	GenerationJobsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "generation_jobs_total",
+			Help: "Total generation job attempts by outcome",
+		},
+		[]string{"status"},
+	)
+
 	// MessagesTotal tracks total messages sent.
 	MessagesTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -112,6 +148,19 @@ func RecordLLMStream(model, status string, duration float64, tokensIn, tokensOut
 	LLMTokensTotal.WithLabelValues(model, "out").Add(float64(tokensOut))
 }
 
+// RecordLLMCacheTokens records prompt tokens read from and written to a
+// provider's prompt cache.
+func RecordLLMCacheTokens(model string, read, write int) {
+	LLMTokensTotal.WithLabelValues(model, "cache_read").Add(float64(read))
+	LLMTokensTotal.WithLabelValues(model, "cache_write").Add(float64(write))
+}
+
+// RecordCircuitState records a circuit breaker state change.
+func RecordCircuitState(provider, model, state string, value float64) {
+	LLMCircuitState.WithLabelValues(provider, model).Set(value)
+	LLMCircuitTransitions.WithLabelValues(provider, model, state).Inc()
+}
+
 // IncrementSSEConnections increments the active SSE connection count.
 func IncrementSSEConnections() {
 	SSEConnectionsActive.Inc()